@@ -0,0 +1,98 @@
+package router
+
+import "regexp"
+
+// Constraint reports whether a param value is accepted
+type Constraint func(value string) bool
+
+// builtinConstraints are the named constraints usable as :name{type}
+var builtinConstraints = map[string]Constraint{
+	"int":   isInt,
+	"uuid":  isUUID,
+	"slug":  isSlug,
+	"alpha": isAlpha,
+}
+
+// compileConstraint returns the constraint for the expression between
+// the braces of :name{expr}, a built-in type or a regular expression
+func compileConstraint(expr string) (Constraint, error) {
+	if constraint, ok := builtinConstraints[expr]; ok {
+		return constraint, nil
+	}
+
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// isInt accepts decimal digits
+func isInt(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlpha accepts ASCII letters
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if !isLetter(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSlug accepts lowercase letters and digits separated by single dashes
+func isSlug(s string) bool {
+	if s == "" || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i, c := range []byte(s) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && s[i-1] != '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID accepts UUIDs in the canonical 8-4-4-4-12 hex form
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range []byte(s) {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !isHex(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
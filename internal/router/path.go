@@ -0,0 +1,270 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CleanPath is the URL version of path.Clean, it returns a canonical URL path
+// for p, eliminating . and .. elements.
+//
+// The following rules are applied iteratively until no further processing can
+// be done:
+//  1. Replace multiple slashes with a single slash.
+//  2. Eliminate each . path name element (the current directory).
+//  3. Eliminate each inner .. path name element (the parent directory)
+//     along with the non-.. element that precedes it.
+//  4. Eliminate .. elements that begin a rooted path:
+//     that is, replace "/.." by "/" at the beginning of a path.
+//
+// If the result of this process is an empty string, "/" is returned.
+// A trailing slash is preserved.
+func CleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	n := len(p)
+	buf := make([]byte, 0, n+1)
+
+	// Invariants:
+	//      reading from path; r is index of next byte to process.
+	//      writing to buf; w is index of next byte to write.
+
+	// path must start with '/'
+	r := 1
+	w := 1
+	buf = append(buf, '/')
+	if p[0] != '/' {
+		r = 0
+		buf = buf[:n+1]
+	} else {
+		buf = buf[:n]
+	}
+
+	trailing := n > 1 && p[n-1] == '/'
+
+	for r < n {
+		switch {
+		case p[r] == '/':
+			// empty path element, trailing slash is added after the end
+			r++
+
+		case p[r] == '.' && r+1 == n:
+			trailing = true
+			r++
+
+		case p[r] == '.' && p[r+1] == '/':
+			// . element
+			r += 2
+
+		case p[r] == '.' && p[r+1] == '.' && (r+2 == n || p[r+2] == '/'):
+			// .. element: remove to last /
+			r += 3
+
+			if w > 1 {
+				// can backtrack
+				w--
+				for w > 1 && buf[w] != '/' {
+					w--
+				}
+			}
+
+		default:
+			// real path element.
+			// add slash if needed
+			if w > 1 {
+				buf[w] = '/'
+				w++
+			}
+
+			// copy element
+			for r < n && p[r] != '/' {
+				buf[w] = p[r]
+				w++
+				r++
+			}
+		}
+	}
+
+	// re-append trailing slash
+	if trailing && w > 1 {
+		buf[w] = '/'
+		w++
+	}
+
+	return string(buf[:w])
+}
+
+// Pattern is a parsed route pattern. Routes are parsed once when they are
+// registered; the pattern is kept to build paths and fix redirects.
+type Pattern struct {
+	path  string
+	parts []part
+}
+
+// ParsePattern parses a route pattern, compiling its constraints
+func ParsePattern(path string) (*Pattern, error) {
+	parts, err := parsePattern(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{path: path, parts: parts}, nil
+}
+
+// String returns the pattern as written
+func (p *Pattern) String() string {
+	return p.path
+}
+
+// Build builds a request path from the pattern, replacing each :param and
+// *catchAll with the value returned by param. Values are escaped; a
+// catch-all value keeps its slashes. Values must satisfy the param's
+// constraint.
+func (p *Pattern) Build(param func(name string) (string, bool)) (string, error) {
+	var sb strings.Builder
+	for _, part := range p.parts {
+		if part.kind == static {
+			sb.WriteString(part.text)
+			continue
+		}
+
+		value, ok := param(part.name)
+		if !ok {
+			return "", fmt.Errorf("missing value for parameter %q", part.name)
+		}
+
+		if part.kind == catchAll {
+			// The catch-all includes the '/' in front of it
+			segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for _, segment := range segments {
+				sb.WriteByte('/')
+				sb.WriteString(url.PathEscape(segment))
+			}
+			continue
+		}
+
+		if value == "" {
+			return "", fmt.Errorf("empty value for parameter %q", part.name)
+		}
+		if part.constraint != nil && !part.constraint(value) {
+			return "", fmt.Errorf("value %q for parameter %q does not match its constraint", value, part.name)
+		}
+		sb.WriteString(url.PathEscape(value))
+	}
+	return sb.String(), nil
+}
+
+// part is a static text or a wildcard of a route pattern
+type part struct {
+	kind       nodeType
+	text       string // static text, or the wildcard as written
+	name       string // wildcard name
+	constraint Constraint
+}
+
+// parsePattern splits a route pattern into static texts and wildcards.
+// A :param name consists of letters, digits and underscores and may be
+// followed by a {constraint}. Params can appear anywhere in a segment as long
+// as they are separated by static text, e.g. /files/:name.:ext or
+// /v:version/users. A *catchAll must make up the last segment; its part
+// includes the '/' in front of it.
+func parsePattern(path string) ([]part, error) {
+	if path == "" || path[0] != '/' {
+		return nil, fmt.Errorf("path must begin with '/' in path '%s'", path)
+	}
+
+	var parts []part
+	start := 0 // start of the pending static text
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case ':':
+			end := i + 1
+			for end < len(path) && isNameChar(path[end]) {
+				end++
+			}
+			name := path[i+1 : end]
+			if name == "" {
+				return nil, fmt.Errorf("wildcards must be named with a non-empty name in path '%s'", path)
+			}
+			if i == start && len(parts) > 0 {
+				return nil, fmt.Errorf("params must be separated by static text, found ':%s' right after '%s' in path '%s'",
+					name, parts[len(parts)-1].text, path)
+			}
+
+			var constraint Constraint
+			if end < len(path) && path[end] == '{' {
+				close := closingBrace(path, end)
+				if close < 0 {
+					return nil, fmt.Errorf("unterminated constraint for ':%s' in path '%s'", name, path)
+				}
+				expr := path[end+1 : close]
+				if expr == "" {
+					return nil, fmt.Errorf("empty constraint for ':%s' in path '%s'", name, path)
+				}
+				var err error
+				if constraint, err = compileConstraint(expr); err != nil {
+					return nil, fmt.Errorf("invalid constraint for ':%s' in path '%s': %v", name, path, err)
+				}
+				end = close + 1
+			}
+
+			if i > start {
+				parts = append(parts, part{kind: static, text: path[start:i]})
+			}
+			parts = append(parts, part{kind: param, text: path[i:end], name: name, constraint: constraint})
+			i, start = end, end
+
+		case '*':
+			if path[i-1] != '/' {
+				return nil, fmt.Errorf("no / before catch-all in path '%s'", path)
+			}
+			name := path[i+1:]
+			if strings.IndexByte(name, '/') >= 0 {
+				return nil, fmt.Errorf("catch-all routes are only allowed at the end of the path in path '%s'", path)
+			}
+			if name == "" {
+				return nil, fmt.Errorf("wildcards must be named with a non-empty name in path '%s'", path)
+			}
+			if strings.ContainsAny(name, ":*{}") {
+				return nil, fmt.Errorf("invalid catch-all name '%s' in path '%s'", name, path)
+			}
+
+			if i-1 > start {
+				parts = append(parts, part{kind: static, text: path[start : i-1]})
+			}
+			return append(parts, part{kind: catchAll, text: path[i-1:], name: name}), nil
+
+		default:
+			i++
+		}
+	}
+
+	if start < len(path) {
+		parts = append(parts, part{kind: static, text: path[start:]})
+	}
+	return parts, nil
+}
+
+// isNameChar reports whether c can be part of a param name
+func isNameChar(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9' || c == '_'
+}
+
+// closingBrace returns the index of the '}' closing the '{' at open, or -1
+func closingBrace(path string, open int) int {
+	depth := 0
+	for i := open; i < len(path); i++ {
+		switch path[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
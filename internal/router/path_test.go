@@ -0,0 +1,55 @@
+package router
+
+import (
+	"testing"
+)
+
+func TestPatternBuild(t *testing.T) {
+	tests := []struct {
+		pattern string
+		params  map[string]string
+		path    string
+		err     bool
+	}{
+		{"/users/:id", map[string]string{"id": "42"}, "/users/42", false},
+		{"/users/:id{int}", map[string]string{"id": "42"}, "/users/42", false},
+		{"/users/:id{int}", map[string]string{"id": "abc"}, "", true},
+		{"/users/:id{[a-z]+}", map[string]string{"id": "abc"}, "/users/abc", false},
+		{"/users/:id", map[string]string{}, "", true},
+		{"/users/:id", map[string]string{"id": ""}, "", true},
+		{"/files/:name.:ext", map[string]string{"name": "a b", "ext": "txt"}, "/files/a%20b.txt", false},
+		{"/static/*filepath", map[string]string{"filepath": "/css/a b.css"}, "/static/css/a%20b.css", false},
+	}
+	for _, tt := range tests {
+		pattern, err := ParsePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("ParsePattern(%q): %v", tt.pattern, err)
+		}
+		path, err := pattern.Build(func(name string) (string, bool) {
+			value, ok := tt.params[name]
+			return value, ok
+		})
+		if (err != nil) != tt.err || path != tt.path {
+			t.Errorf("Build(%q, %v) = %q, %v, want %q, error %v", tt.pattern, tt.params, path, err, tt.path, tt.err)
+		}
+	}
+}
+
+func TestPatternParsedOnce(t *testing.T) {
+	root := newTree(t, "/Users/:id{[0-9]+}/posts/:slug{[a-z-]+}")
+	pattern, err := ParsePattern("/users/:id{[0-9]+}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	param := func(string) (string, bool) { return "42", true }
+
+	// Compiling the regexes would allocate far more than this
+	if allocs := testing.AllocsPerRun(100, func() { pattern.Build(param) }); allocs > 3 {
+		t.Errorf("Build: %v allocs, want at most 3", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		root.FindCaseInsensitivePath("/users/42/posts/hello-world", false)
+	}); allocs > 8 {
+		t.Errorf("FindCaseInsensitivePath: %v allocs, want at most 8", allocs)
+	}
+}
@@ -1,5 +1,13 @@
 package router
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
 // nodeType represents the type of route node
 type nodeType uint8
 
@@ -9,329 +17,655 @@ const (
 	catchAll                 // *param
 )
 
-// HandlerFunc is a function that handles HTTP requests
-type HandlerFunc interface{}
+// Node represents a node in the radix tree, storing values of type T
+type Node[T any] struct {
+	path     string     // static text, or the wildcard as written in the route
+	foldPath string     // path with its case folded, see foldCase
+	indices  string     // first byte of each static child
+	children []*Node[T] // static children
+	params   []*Node[T] // param children, constrained ones first
+	catchAll *Node[T]   // catch-all child
+	value    *T         // route stored at this node, nil if none
+	nType    nodeType
+	fullPath string
+	parts    []part // parsed fullPath, to rebuild fixed paths
+
+	// wildcard nodes only
+	paramName  string
+	constraint Constraint // nil if the param accepts any value
+}
+
+// Param is a single route param
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params are the params of a matched route, in the order of the pattern
+type Params []Param
+
+// Get returns the value of the first param with the given name
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
 
-// Node represents a node in the radix tree
-type Node struct {
-	path      string
-	indices   string
-	children  []*Node
-	handlers  []HandlerFunc
-	priority  uint32
-	nType     nodeType
-	wildChild bool
-	fullPath  string
+// ByName returns the value of the first param with the given name,
+// or an empty string if there is none
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
 }
 
 // NewNode creates a new routing tree node
-func NewNode() *Node {
-	return &Node{}
-}
-
-// AddRoute adds a route to the tree
-func (n *Node) AddRoute(path string, handlers []HandlerFunc) {
-	fullPath := path
-	n.priority++
-
-	// Empty tree
-	if len(n.path) == 0 && len(n.children) == 0 {
-		n.insertChild(path, fullPath, handlers)
-		n.nType = static
-		return
-	}
-
-	parentFullPathIndex := 0
-
-walk:
-	for {
-		// Find the longest common prefix
-		i := longestCommonPrefix(path, n.path)
-
-		// Split edge
-		if i < len(n.path) {
-			child := &Node{
-				path:      n.path[i:],
-				wildChild: n.wildChild,
-				nType:     static,
-				indices:   n.indices,
-				children:  n.children,
-				handlers:  n.handlers,
-				priority:  n.priority - 1,
-				fullPath:  n.fullPath,
-			}
+func NewNode[T any]() *Node[T] {
+	return &Node[T]{}
+}
+
+// ConflictError reports a route that cannot be added because it conflicts
+// with a registered route
+type ConflictError struct {
+	Path     string // pattern of the new route
+	Existing string // pattern of the conflicting registered route
+	Reason   string
+}
+
+// Error implements the error interface
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("path '%s' conflicts with existing route '%s': %s", e.Path, e.Existing, e.Reason)
+}
+
+// AddRoute returns a copy of the tree with the route added, storing value
+// at its node. The tree itself is not modified: nodes along the route are
+// copied and all others shared, so it can be read concurrently.
+// It panics if the path is invalid or conflicts with a registered route.
+func (n *Node[T]) AddRoute(path string, value *T) *Node[T] {
+	root, err := n.InsertRoute(path, value)
+	if err != nil {
+		panic(err.Error())
+	}
+	return root
+}
 
-			n.children = []*Node{child}
-			n.indices = string([]byte{n.path[i]})
-			n.path = path[:i]
-			n.handlers = nil
-			n.wildChild = false
-			n.fullPath = fullPath[:parentFullPathIndex+i]
+// InsertRoute is like AddRoute but returns an error instead of panicking.
+// A conflict with a registered route is reported as a *ConflictError.
+func (n *Node[T]) InsertRoute(path string, value *T) (*Node[T], error) {
+	pattern, err := ParsePattern(path)
+	if err != nil {
+		return n, err
+	}
+	return n.InsertPattern(pattern, value)
+}
+
+// InsertPattern is like InsertRoute for an already parsed pattern
+func (n *Node[T]) InsertPattern(pattern *Pattern, value *T) (*Node[T], error) {
+	if err := n.checkConflict(pattern.parts, pattern.path); err != nil {
+		return n, err
+	}
+
+	root := n.clone()
+	n = root
+	for _, part := range pattern.parts {
+		if part.kind == static {
+			n = n.insertStatic(part.text)
+		} else {
+			n = n.insertWild(part)
 		}
+	}
+	n.value = value
+	n.fullPath = pattern.path
+	n.parts = pattern.parts
+	return root, nil
+}
+
+// RemoveRoute returns a copy of the tree without the route registered for
+// path, and the removed value. Like AddRoute, it doesn't modify the tree.
+// If no route is registered for path, the tree itself and nil are returned.
+func (n *Node[T]) RemoveRoute(path string) (*Node[T], *T) {
+	parts, err := parsePattern(path)
+	if err != nil {
+		return n, nil
+	}
 
-		// Make new node a child of this node
-		if i < len(path) {
-			path = path[i:]
-
-			if n.wildChild {
-				parentFullPathIndex += len(n.path)
-				n = n.children[0]
-				n.priority++
-
-				// Check if the wildcard matches
-				if len(path) >= len(n.path) && n.path == path[:len(n.path)] &&
-					(len(n.path) >= len(path) || path[len(n.path)] == '/') {
-					continue walk
-				} else {
-					panic("conflict with wildcard route")
+	// Copy the nodes along the route, remembering them to prune afterwards
+	root := n.clone()
+	nodes := []*Node[T]{root}
+	cur := root
+	for _, part := range parts {
+		switch part.kind {
+		case param:
+			i := slices.IndexFunc(cur.params, func(p *Node[T]) bool { return p.path == part.text })
+			if i < 0 {
+				return n, nil
+			}
+			cur.params[i] = cur.params[i].clone()
+			cur = cur.params[i]
+			nodes = append(nodes, cur)
+
+		case catchAll:
+			if cur.catchAll == nil || cur.catchAll.path != part.text {
+				return n, nil
+			}
+			cur.catchAll = cur.catchAll.clone()
+			cur = cur.catchAll
+			nodes = append(nodes, cur)
+
+		default:
+			for s := part.text; len(s) > 0; {
+				i := strings.IndexByte(cur.indices, s[0])
+				if i < 0 || !strings.HasPrefix(s, cur.children[i].path) {
+					return n, nil
 				}
+				cur.children[i] = cur.children[i].clone()
+				cur = cur.children[i]
+				s = s[len(cur.path):]
+				nodes = append(nodes, cur)
 			}
+		}
+	}
+
+	value := cur.value
+	if value == nil {
+		return n, nil
+	}
+	cur.value = nil
+	cur.fullPath = ""
+	cur.parts = nil
+
+	// Remove the nodes left without routes
+	i := len(nodes) - 1
+	for ; i > 0 && nodes[i].isEmpty(); i-- {
+		nodes[i-1].removeChild(nodes[i])
+	}
+
+	// Merge a static node that was only kept for a single static child
+	if last := nodes[i]; i > 0 && last.nType == static && last.value == nil &&
+		len(last.children) == 1 && len(last.params) == 0 && last.catchAll == nil {
+		child := last.children[0]
+		last.path += child.path
+		last.foldPath += child.foldPath
+		last.indices = child.indices
+		last.children = child.children
+		last.params = child.params
+		last.catchAll = child.catchAll
+		last.value = child.value
+		last.fullPath = child.fullPath
+		last.parts = child.parts
+	}
+	return root, value
+}
+
+// clone returns a copy of n that can be modified without affecting n
+func (n *Node[T]) clone() *Node[T] {
+	c := *n
+	c.children = slices.Clone(n.children)
+	c.params = slices.Clone(n.params)
+	return &c
+}
+
+// isEmpty reports whether no route is registered at or below n
+func (n *Node[T]) isEmpty() bool {
+	return n.value == nil && len(n.children) == 0 && len(n.params) == 0 && n.catchAll == nil
+}
 
-			idxc := path[0]
+// removeChild removes the child node from n
+func (n *Node[T]) removeChild(child *Node[T]) {
+	switch child.nType {
+	case param:
+		n.params = slices.DeleteFunc(n.params, func(p *Node[T]) bool { return p == child })
+	case catchAll:
+		n.catchAll = nil
+	default:
+		i := slices.Index(n.children, child)
+		n.children = slices.Delete(n.children, i, i+1)
+		n.indices = n.indices[:i] + n.indices[i+1:]
+	}
+}
 
-			// Check if a child with the next path byte exists
-			for i, c := range []byte(n.indices) {
-				if c == idxc {
-					parentFullPathIndex += len(n.path)
-					i = n.incrementChildPrio(i)
-					n = n.children[i]
-					continue walk
+// checkConflict walks the tree along parts without modifying it and
+// reports a conflict with a registered route
+func (n *Node[T]) checkConflict(parts []part, fullPath string) error {
+	for _, part := range parts {
+		switch part.kind {
+		case param:
+			child, conflict := n.findParam(part)
+			if conflict != nil {
+				return &ConflictError{
+					Path:     fullPath,
+					Existing: conflict.anyRoute(),
+					Reason:   fmt.Sprintf("wildcard '%s' conflicts with wildcard '%s'", part.text, conflict.path),
 				}
 			}
+			if child == nil {
+				return nil
+			}
+			n = child
 
-			// Otherwise insert it
-			if idxc != ':' && idxc != '*' {
-				n.indices += string([]byte{idxc})
-				child := &Node{
-					fullPath: fullPath,
+		case catchAll:
+			if n.catchAll == nil {
+				return nil
+			}
+			if n.catchAll.path != part.text {
+				return &ConflictError{
+					Path:     fullPath,
+					Existing: n.catchAll.anyRoute(),
+					Reason:   fmt.Sprintf("wildcard '%s' conflicts with wildcard '%s'", part.text[1:], n.catchAll.path[1:]),
+				}
+			}
+			n = n.catchAll
+
+		default:
+			for s := part.text; len(s) > 0; {
+				child := n.staticChild(s[0])
+				if child == nil {
+					return nil
+				}
+				c := longestCommonPrefix(s, child.path)
+				if c < len(child.path) {
+					// The edge is split and the rest of the path is new
+					return nil
 				}
-				n.children = append(n.children, child)
-				n.incrementChildPrio(len(n.indices) - 1)
 				n = child
+				s = s[c:]
 			}
+		}
+	}
 
-			n.insertChild(path, fullPath, handlers)
-			return
+	if n.value != nil {
+		return &ConflictError{
+			Path:     fullPath,
+			Existing: n.fullPath,
+			Reason:   "a route is already registered for this path",
 		}
+	}
+	return nil
+}
 
-		// Otherwise add handlers to current node
-		if n.handlers != nil {
-			panic("handlers are already registered for path '" + fullPath + "'")
+// findParam returns the param child of n for part. Params at the same
+// position must differ in their constraint, since that is the only way to
+// tell them apart; a param with the same constraint but another name is
+// returned as conflict.
+func (n *Node[T]) findParam(part part) (child, conflict *Node[T]) {
+	for _, p := range n.params {
+		if p.path == part.text {
+			return p, nil
+		}
+		if p.path[len(p.paramName)+1:] == part.text[len(part.name)+1:] {
+			return nil, p
 		}
-		n.handlers = handlers
-		n.fullPath = fullPath
-		return
 	}
+	return nil, nil
 }
 
-// insertChild inserts a child node
-func (n *Node) insertChild(path, fullPath string, handlers []HandlerFunc) {
-	for {
-		// Find prefix until first wildcard
-		wildcard, i, valid := findWildcard(path)
+// insertStatic inserts the static text s below n and returns its node
+func (n *Node[T]) insertStatic(s string) *Node[T] {
+	// The folded text has the same length, so it is split at the same offsets
+	fs := foldCase(s)
+	for len(s) > 0 {
+		i := strings.IndexByte(n.indices, s[0])
 		if i < 0 {
-			break
+			child := &Node[T]{path: s, foldPath: fs}
+			n.indices += s[:1]
+			n.children = append(n.children, child)
+			return child
 		}
 
-		if !valid {
-			panic("only one wildcard per path segment is allowed")
+		// n is a copy, so its child can be replaced by a copy too
+		n.children[i] = n.children[i].clone()
+		child := n.children[i]
+
+		// Split the edge at the longest common prefix
+		c := longestCommonPrefix(s, child.path)
+		if c < len(child.path) {
+			rest := *child
+			rest.path = child.path[c:]
+			rest.foldPath = child.foldPath[c:]
+			*child = Node[T]{
+				path:     child.path[:c],
+				foldPath: child.foldPath[:c],
+				indices:  rest.path[:1],
+				children: []*Node[T]{&rest},
+			}
 		}
+		n = child
+		s, fs = s[c:], fs[c:]
+	}
+	return n
+}
 
-		// Check if the wildcard has a name
-		if len(wildcard) < 2 {
-			panic("wildcards must be named with a non-empty name")
+// insertWild inserts the wildcard part below n and returns its node
+func (n *Node[T]) insertWild(part part) *Node[T] {
+	if part.kind == catchAll {
+		if n.catchAll == nil {
+			n.catchAll = newWild[T](part)
+		} else {
+			n.catchAll = n.catchAll.clone()
 		}
+		return n.catchAll
+	}
 
-		// Check if this node has existing children
-		if len(n.children) > 0 {
-			panic("wildcard segment conflicts with existing children")
+	if i := slices.IndexFunc(n.params, func(p *Node[T]) bool { return p.path == part.text }); i >= 0 {
+		n.params[i] = n.params[i].clone()
+		return n.params[i]
+	}
+
+	// Constrained params are tried before the one accepting any value
+	child := newWild[T](part)
+	i := len(n.params)
+	if part.constraint != nil {
+		for i > 0 && n.params[i-1].constraint == nil {
+			i--
 		}
+	}
+	n.params = append(n.params, nil)
+	copy(n.params[i+1:], n.params[i:])
+	n.params[i] = child
+	return child
+}
 
-		// param
-		if wildcard[0] == ':' {
-			if i > 0 {
-				n.path = path[:i]
-				path = path[i:]
-			}
+// newWild creates the node of a wildcard part
+func newWild[T any](part part) *Node[T] {
+	return &Node[T]{
+		path:       part.text,
+		nType:      part.kind,
+		paramName:  part.name,
+		constraint: part.constraint,
+	}
+}
 
-			n.wildChild = true
-			child := &Node{
-				nType:    param,
-				path:     wildcard,
-				fullPath: fullPath,
-			}
-			n.children = []*Node{child}
-			n = child
-			n.priority++
-
-			// If the path doesn't end with the wildcard, then there
-			// will be another non-wildcard subpath starting with '/'
-			if len(wildcard) < len(path) {
-				path = path[len(wildcard):]
-				child := &Node{
-					priority: 1,
-					fullPath: fullPath,
-				}
-				n.children = []*Node{child}
-				n = child
-				continue
-			}
+// staticChild returns the static child starting with c
+func (n *Node[T]) staticChild(c byte) *Node[T] {
+	if i := strings.IndexByte(n.indices, c); i >= 0 {
+		return n.children[i]
+	}
+	return nil
+}
 
-			// Otherwise we're done
-			n.handlers = handlers
-			return
+// anyRoute returns the path of a route registered below n, for error messages
+func (n *Node[T]) anyRoute() string {
+	if n.value != nil {
+		return n.fullPath
+	}
+	for _, child := range n.children {
+		if path := child.anyRoute(); path != "" {
+			return path
 		}
-
-		// catchAll
-		if i+len(wildcard) != len(path) {
-			panic("catch-all routes are only allowed at the end of the path")
+	}
+	for _, child := range n.params {
+		if path := child.anyRoute(); path != "" {
+			return path
 		}
+	}
+	if n.catchAll != nil {
+		return n.catchAll.anyRoute()
+	}
+	return ""
+}
 
-		if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
-			panic("catch-all conflicts with existing handle for the path segment root")
+// GetValue returns the value of the route matching path, or nil.
+// The params of the route are appended to ps; they are not touched
+// if no route matches.
+func (n *Node[T]) GetValue(path string, ps *Params) *T {
+	if found := n.match(path, path, ps, false); found != nil {
+		return found.value
+	}
+	return nil
+}
+
+// GetValueCaseInsensitive is like GetValue, but compares the static text
+// of the routes case-insensitively. Param values keep the case of path.
+func (n *Node[T]) GetValueCaseInsensitive(path string, ps *Params) *T {
+	if found := n.match(path, foldCase(path), ps, true); found != nil {
+		return found.value
+	}
+	return nil
+}
+
+// match returns the node holding the route for path below n, appending the
+// matched params to ps. The static text of the routes is compared with key,
+// which is path itself or, if fold is true, path with its case folded.
+// Param values are taken from path.
+//
+// Static children are preferred over params, and params over a catch-all.
+// If a branch fails deeper down, the lookup backtracks and tries the next
+// one, so /users/new/edit and /users/:id/edit can both be registered.
+// Params at the same position are tried in registration order, constrained
+// ones first.
+//
+// A param matches at least one byte and never a '/'. When a param is
+// followed by static text in the same segment, it ends at the last or else
+// the first occurrence of that text in the segment, whichever lets the rest
+// of the route match; a route continuing with static text in the segment is
+// preferred over one where the param takes the whole segment. So for
+// /files/:name.:ext, "app.min.js" gives name=app.min and ext=js, and
+// /images/:id.png matches "logo.png" even if /images/:id is registered too.
+// Trying only these two split points keeps the work linear in the length
+// of the segment, however many params it holds.
+func (n *Node[T]) match(path, key string, ps *Params, fold bool) *Node[T] {
+	switch n.nType {
+	case param:
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end == 0 {
+			return nil
 		}
 
-		// currently fixed width 1 for '/'
-		i--
-		if path[i] != '/' {
-			panic("no / before catch-all")
+		// Static text following the param in the same segment
+		if len(n.children) > 0 {
+			var buf [8]int
+			for _, e := range n.splitPoints(key[:end], buf[:0], fold) {
+				if found := n.matchParam(path, key, e, ps, fold); found != nil {
+					return found
+				}
+			}
 		}
 
-		n.path = path[:i]
+		// The param takes the whole segment
+		return n.matchParam(path, key, end, ps, fold)
 
-		// First node: catchAll node with empty path
-		child := &Node{
-			wildChild: true,
-			nType:     catchAll,
-			fullPath:  fullPath,
+	case catchAll:
+		if path == "" || path[0] != '/' {
+			return nil
 		}
-		n.children = []*Node{child}
-		n.indices = string('/')
-		n = child
-		n.priority++
-
-		// Second node: node holding the variable
-		child = &Node{
-			path:     path[i:],
-			nType:    catchAll,
-			handlers: handlers,
-			priority: 1,
-			fullPath: fullPath,
-		}
-		n.children = []*Node{child}
+		*ps = append(*ps, Param{Key: n.paramName, Value: path})
+		return n
+	}
 
-		return
+	prefix := n.path
+	if fold {
+		prefix = n.foldPath
+	}
+	if len(key) < len(prefix) || key[:len(prefix)] != prefix {
+		return nil
 	}
 
-	// If no wildcard was found, simply insert the path and handlers
-	n.path = path
-	n.handlers = handlers
-	n.fullPath = fullPath
+	path, key = path[len(prefix):], key[len(prefix):]
+	if path == "" {
+		if n.value != nil {
+			return n
+		}
+		return nil
+	}
+	return n.matchChildren(path, key, ps, fold)
 }
 
-// GetValue retrieves handlers and params for a given path
-func (n *Node) GetValue(path string) (handlers []HandlerFunc, params map[string]string, fullPath string) {
-	params = make(map[string]string)
+// matchParam matches the param node n with the value path[:end] and the
+// rest of path below it
+func (n *Node[T]) matchParam(path, key string, end int, ps *Params, fold bool) *Node[T] {
+	value := path[:end]
+	if n.constraint != nil && !n.constraint(value) {
+		return nil
+	}
 
-walk:
-	for {
-		prefix := n.path
-		if len(path) > len(prefix) {
-			if path[:len(prefix)] == prefix {
-				path = path[len(prefix):]
+	mark := len(*ps)
+	*ps = append(*ps, Param{Key: n.paramName, Value: value})
 
-				// Try all the non-wildcard children first
-				idxc := path[0]
-				for i, c := range []byte(n.indices) {
-					if c == idxc {
-						n = n.children[i]
-						continue walk
-					}
-				}
+	if end == len(path) {
+		if n.value != nil {
+			return n
+		}
+	} else if found := n.matchChildren(path[end:], key[end:], ps, fold); found != nil {
+		return found
+	}
 
-				// If there is a wildcard child, use it
-				if n.wildChild {
-					n = n.children[0]
-					switch n.nType {
-					case param:
-						// Find end of param (either '/' or end of path)
-						end := 0
-						for end < len(path) && path[end] != '/' {
-							end++
-						}
-
-						// Save param value
-						params[n.path[1:]] = path[:end]
-
-						// We need to go deeper!
-						if end < len(path) {
-							if len(n.children) > 0 {
-								path = path[end:]
-								n = n.children[0]
-								continue walk
-							}
-
-							// ... but we can't
-							return nil, nil, ""
-						}
-
-						handlers = n.handlers
-						fullPath = n.fullPath
-						return
-
-					case catchAll:
-						// Save param value
-						params[n.path[2:]] = path
-
-						handlers = n.handlers
-						fullPath = n.fullPath
-						return
-
-					default:
-						panic("invalid node type")
-					}
-				}
+	*ps = (*ps)[:mark]
+	return nil
+}
 
-				// Nothing found
-				return nil, nil, ""
+// matchChildren matches the non-empty path against the children of n
+func (n *Node[T]) matchChildren(path, key string, ps *Params, fold bool) *Node[T] {
+	if !fold {
+		if child := n.staticChild(key[0]); child != nil {
+			if found := child.match(path, key, ps, fold); found != nil {
+				return found
 			}
-		} else if path == prefix {
-			// We should have reached the node containing the handlers
-			if handlers = n.handlers; handlers != nil {
-				fullPath = n.fullPath
-				return
+		}
+	} else {
+		// Children that differ only in case share a folded first byte
+		for _, child := range n.children {
+			if child.foldPath[0] == key[0] {
+				if found := child.match(path, key, ps, fold); found != nil {
+					return found
+				}
 			}
+		}
+	}
+
+	for _, child := range n.params {
+		if found := child.match(path, key, ps, fold); found != nil {
+			return found
+		}
+	}
+	if n.catchAll != nil {
+		return n.catchAll.match(path, key, ps, fold)
+	}
+	return nil
+}
 
-			// No handlers registered for this path
-			return nil, nil, ""
+// splitPoints appends to points the offsets in segment at which the param
+// node n may end: the last and the first occurrence of the text of each
+// static child within the segment. They are returned longest value first.
+func (n *Node[T]) splitPoints(segment string, points []int, fold bool) []int {
+	for _, child := range n.children {
+		text := child.path
+		if fold {
+			text = child.foldPath
+		}
+		if i := strings.IndexByte(text, '/'); i >= 0 {
+			text = text[:i]
+		}
+		if text == "" {
+			continue
 		}
 
-		// Nothing found
-		return nil, nil, ""
+		// A param value is never empty, so the text can't start at 0
+		last := strings.LastIndex(segment, text)
+		if last <= 0 {
+			continue
+		}
+		points = insertPoint(points, last)
+		if first := 1 + strings.Index(segment[1:], text); first < last {
+			points = insertPoint(points, first)
+		}
 	}
+	return points
 }
 
-// incrementChildPrio increments the priority of the child at the given index
-func (n *Node) incrementChildPrio(pos int) int {
-	cs := n.children
-	cs[pos].priority++
-	prio := cs[pos].priority
+// insertPoint inserts p into the points sorted in descending order,
+// unless it is already there
+func insertPoint(points []int, p int) []int {
+	i := 0
+	for i < len(points) && points[i] > p {
+		i++
+	}
+	if i < len(points) && points[i] == p {
+		return points
+	}
+	return slices.Insert(points, i, p)
+}
 
-	// Adjust position (move to front)
-	newPos := pos
-	for ; newPos > 0 && cs[newPos-1].priority < prio; newPos-- {
-		// Swap node positions
-		cs[newPos-1], cs[newPos] = cs[newPos], cs[newPos-1]
+// Walk calls fn for every route registered in the tree
+func (n *Node[T]) Walk(fn func(fullPath string, value *T)) {
+	if n.value != nil {
+		fn(n.fullPath, n.value)
+	}
+	for _, child := range n.children {
+		child.Walk(fn)
 	}
+	for _, child := range n.params {
+		child.Walk(fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.Walk(fn)
+	}
+}
 
-	// Build new index char string
-	if newPos != pos {
-		n.indices = n.indices[:newPos] + // Unchanged prefix
-			n.indices[pos:pos+1] + // The index char we move
-			n.indices[newPos:pos] + n.indices[pos+1:] // Rest without char at 'pos'
+// TrailingSlashMatch reports whether a route exists for path with the
+// trailing slash added or removed
+func (n *Node[T]) TrailingSlashMatch(path string, caseInsensitive bool) bool {
+	if path == "" || path == "/" {
+		return false
+	}
+	if path[len(path)-1] == '/' {
+		path = path[:len(path)-1]
+	} else {
+		path += "/"
 	}
 
-	return newPos
+	var ps Params
+	if caseInsensitive {
+		return n.GetValueCaseInsensitive(path, &ps) != nil
+	}
+	return n.GetValue(path, &ps) != nil
+}
+
+// FindCaseInsensitivePath makes a case-insensitive lookup of the given path
+// and tries to find a registered route. It returns the path with the case of
+// the registered route and true if one was found.
+// If fixTrailingSlash is true, a missing or extra trailing slash is fixed too.
+func (n *Node[T]) FindCaseInsensitivePath(path string, fixTrailingSlash bool) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	if out, ok := n.findCaseInsensitivePath(path); ok {
+		return out, true
+	}
+	if !fixTrailingSlash || path == "/" {
+		return "", false
+	}
+	if path[len(path)-1] == '/' {
+		path = path[:len(path)-1]
+	} else {
+		path += "/"
+	}
+	return n.findCaseInsensitivePath(path)
+}
+
+// findCaseInsensitivePath matches path case-insensitively and rebuilds it
+// from the static text of the registered route and the param values
+func (n *Node[T]) findCaseInsensitivePath(path string) (string, bool) {
+	var ps Params
+	found := n.match(path, foldCase(path), &ps, true)
+	if found == nil {
+		return "", false
+	}
+
+	var sb strings.Builder
+	for _, part := range found.parts {
+		if part.kind == static {
+			sb.WriteString(part.text)
+			continue
+		}
+		sb.WriteString(ps[0].Value)
+		ps = ps[1:]
+	}
+	return sb.String(), true
 }
 
 // Helper functions
@@ -346,28 +680,30 @@ func longestCommonPrefix(a, b string) int {
 	return i
 }
 
-// findWildcard finds a wildcard segment and checks its validity
-func findWildcard(path string) (wildcard string, i int, valid bool) {
-	// Find start
-	for start, c := range []byte(path) {
-		// A wildcard starts with ':' (param) or '*' (catch-all)
-		if c != ':' && c != '*' {
-			continue
+// foldCase lowercases s for case-insensitive matching. Characters whose
+// lowercase form has a different length in UTF-8 are kept, so byte offsets
+// in the result are the same as in s.
+func foldCase(s string) string {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf || c >= 'A' && c <= 'Z' {
+			return foldCaseSlow(s, i)
 		}
+	}
+	return s
+}
 
-		// Find end and check for invalid characters
-		valid = true
-		for end, c := range []byte(path[start+1:]) {
-			switch c {
-			case '/':
-				return path[start : start+1+end], start, valid
-			case ':', '*':
-				valid = false
-			}
+// foldCaseSlow folds the case of s starting at i, see foldCase
+func foldCaseSlow(s string, i int) string {
+	buf := []byte(s)
+	for i < len(buf) {
+		r, size := utf8.DecodeRune(buf[i:])
+		if lower := unicode.ToLower(r); lower != r && utf8.RuneLen(lower) == size {
+			utf8.EncodeRune(buf[i:], lower)
 		}
-		return path[start:], start, valid
+		i += size
 	}
-	return "", -1, false
+	return string(buf)
 }
 
 // min returns the minimum of two integers
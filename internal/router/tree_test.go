@@ -0,0 +1,416 @@
+package router
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTree returns a tree with the routes added, each storing its pattern
+func newTree(t *testing.T, routes ...string) *Node[string] {
+	t.Helper()
+	root := NewNode[string]()
+	for _, route := range routes {
+		route := route
+		root = root.AddRoute(route, &route)
+	}
+	return root
+}
+
+type lookupTest struct {
+	path   string
+	route  string // expected pattern, empty for no match
+	params Params
+}
+
+// checkLookups matches each path against root and compares the result
+func checkLookups(t *testing.T, root *Node[string], tests []lookupTest) {
+	t.Helper()
+	for _, tt := range tests {
+		var ps Params
+		value := root.GetValue(tt.path, &ps)
+
+		route := ""
+		if value != nil {
+			route = *value
+		}
+		if route != tt.route {
+			t.Errorf("GetValue(%q) matched route %q, want %q", tt.path, route, tt.route)
+			continue
+		}
+		if len(ps) != len(tt.params) {
+			t.Errorf("GetValue(%q) params = %v, want %v", tt.path, ps, tt.params)
+			continue
+		}
+		for i := range ps {
+			if ps[i] != tt.params[i] {
+				t.Errorf("GetValue(%q) params = %v, want %v", tt.path, ps, tt.params)
+				break
+			}
+		}
+	}
+}
+
+func TestParamSuffixes(t *testing.T) {
+	root := newTree(t,
+		"/files/:name.:ext",
+		"/files/:name.:ext/download",
+		"/images/:id",
+		"/images/:id.png",
+		"/archives/:name.tar.gz",
+		"/v:version/users",
+		"/p/:a-:b.:c",
+	)
+
+	checkLookups(t, root, []lookupTest{
+		{"/files/app.js", "/files/:name.:ext", Params{{"name", "app"}, {"ext", "js"}}},
+		{"/files/app.min.js", "/files/:name.:ext", Params{{"name", "app.min"}, {"ext", "js"}}},
+		{"/files/app.min.js/download", "/files/:name.:ext/download", Params{{"name", "app.min"}, {"ext", "js"}}},
+		{"/files/app", "", nil},
+		{"/files/.js", "", nil},
+		{"/files/app.", "", nil},
+		{"/images/logo.png", "/images/:id.png", Params{{"id", "logo"}}},
+		{"/images/logo.png.png", "/images/:id.png", Params{{"id", "logo.png"}}},
+		{"/images/logo.jpg", "/images/:id", Params{{"id", "logo.jpg"}}},
+		{"/archives/src.tar.gz", "/archives/:name.tar.gz", Params{{"name", "src"}}},
+		{"/archives/src.v2.tar.gz", "/archives/:name.tar.gz", Params{{"name", "src.v2"}}},
+		{"/v2/users", "/v:version/users", Params{{"version", "2"}}},
+		{"/v/users", "", nil},
+		{"/p/x-y.z", "/p/:a-:b.:c", Params{{"a", "x"}, {"b", "y"}, {"c", "z"}}},
+		{"/p/x-y-z.w.v", "/p/:a-:b.:c", Params{{"a", "x-y"}, {"b", "z.w"}, {"c", "v"}}},
+	})
+}
+
+func TestParamSuffixAdversarial(t *testing.T) {
+	root := newTree(t,
+		"/p/:a.:b.:c.:d.x",
+		"/files/:name.:ext/download",
+	)
+
+	paths := []string{
+		"/p/" + strings.Repeat("a.", 300) + "y",
+		"/p/" + strings.Repeat("a.", 500_000) + "y",
+		"/files/" + strings.Repeat("a.", 500_000) + "/nope",
+		"/files/" + strings.Repeat(".", 1_000_000),
+	}
+	for _, path := range paths {
+		start := time.Now()
+		var ps Params
+		if value := root.GetValue(path, &ps); value != nil {
+			t.Errorf("GetValue(%.20q...) matched %q, want no match", path, *value)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("GetValue(%.20q...) of %d bytes took %v", path, len(path), elapsed)
+		}
+	}
+
+	// Long segments that do match are found just as fast
+	long := strings.Repeat("a.", 500_000)
+	checkLookups(t, root, []lookupTest{
+		{"/p/" + long + "x", "/p/:a.:b.:c.:d.x", Params{
+			{"a", "a"}, {"b", "a"}, {"c", "a"}, {"d", long[6 : len(long)-1]},
+		}},
+		{"/files/" + long + "js/download", "/files/:name.:ext/download", Params{
+			{"name", long[:len(long)-1]}, {"ext", "js"},
+		}},
+	})
+}
+
+func TestEmptyPath(t *testing.T) {
+	root := newTree(t, "/", "/users/")
+
+	var ps Params
+	if value := root.GetValue("", &ps); value != nil {
+		t.Errorf("GetValue(\"\") matched %q, want no match", *value)
+	}
+	if root.TrailingSlashMatch("", false) || root.TrailingSlashMatch("", true) {
+		t.Error("TrailingSlashMatch(\"\") = true, want false")
+	}
+	if out, ok := root.FindCaseInsensitivePath("", true); ok {
+		t.Errorf("FindCaseInsensitivePath(\"\") = %q, want no match", out)
+	}
+}
+
+func TestParamConstraints(t *testing.T) {
+	root := newTree(t,
+		"/users/:id{int}",
+		"/users/:slug{slug}",
+		"/users/:name",
+		"/orders/:id{uuid}",
+		"/tags/:tag{alpha}",
+		"/years/:year{[0-9]{4}}",
+		"/files/:name{[a-z]+}.:ext{int}",
+	)
+
+	checkLookups(t, root, []lookupTest{
+		{"/users/42", "/users/:id{int}", Params{{"id", "42"}}},
+		{"/users/hello-world", "/users/:slug{slug}", Params{{"slug", "hello-world"}}},
+		{"/users/Hello", "/users/:name", Params{{"name", "Hello"}}},
+		{"/orders/123e4567-e89b-12d3-a456-426614174000", "/orders/:id{uuid}", Params{{"id", "123e4567-e89b-12d3-a456-426614174000"}}},
+		{"/orders/123e4567", "", nil},
+		{"/tags/go", "/tags/:tag{alpha}", Params{{"tag", "go"}}},
+		{"/tags/go1", "", nil},
+		{"/years/2024", "/years/:year{[0-9]{4}}", Params{{"year", "2024"}}},
+		{"/years/202", "", nil},
+		{"/years/20245", "", nil},
+		{"/files/app.1", "/files/:name{[a-z]+}.:ext{int}", Params{{"name", "app"}, {"ext", "1"}}},
+		{"/files/app.js", "", nil},
+	})
+}
+
+func TestAddRouteErrors(t *testing.T) {
+	root := newTree(t,
+		"/users/:id",
+		"/users/:id{int}/raw",
+		"/files/*path",
+		"/static/new",
+	)
+
+	tests := []struct {
+		path     string
+		existing string // expected conflicting route, empty for invalid patterns
+		err      string
+	}{
+		{"/users/:name", "/users/:id", "wildcard ':name' conflicts with wildcard ':id'"},
+		{"/users/:n{int}", "/users/:id{int}/raw", "wildcard ':n{int}' conflicts with wildcard ':id{int}'"},
+		{"/files/*rest", "/files/*path", "wildcard '*rest' conflicts with wildcard '*path'"},
+		{"/static/new", "/static/new", "a route is already registered for this path"},
+		{"users", "", "path must begin with '/' in path 'users'"},
+		{"/x/:", "", "wildcards must be named with a non-empty name in path '/x/:'"},
+		{"/x/:a:b", "", "params must be separated by static text"},
+		{"/x/*a/b", "", "catch-all routes are only allowed at the end of the path in path '/x/*a/b'"},
+		{"/x*a", "", "no / before catch-all in path '/x*a'"},
+		{"/x/:a{", "", "unterminated constraint"},
+		{"/x/:a{}", "", "empty constraint"},
+		{"/x/:a{[}", "", "invalid constraint"},
+	}
+	for _, tt := range tests {
+		next, err := root.InsertRoute(tt.path, &tt.path)
+		if err == nil {
+			t.Errorf("InsertRoute(%q) succeeded, want an error", tt.path)
+			continue
+		}
+		if next != root {
+			t.Errorf("InsertRoute(%q) returned a new tree with the error", tt.path)
+		}
+
+		conflict, ok := err.(*ConflictError)
+		if tt.existing == "" {
+			if ok {
+				t.Errorf("InsertRoute(%q) error = %v, want an invalid pattern error", tt.path, err)
+			} else if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("InsertRoute(%q) error = %q, want it to contain %q", tt.path, err, tt.err)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("InsertRoute(%q) error = %v, want a *ConflictError", tt.path, err)
+			continue
+		}
+		if conflict.Path != tt.path || conflict.Existing != tt.existing || conflict.Reason != tt.err {
+			t.Errorf("InsertRoute(%q) error = %+v, want existing %q and reason %q", tt.path, *conflict, tt.existing, tt.err)
+		}
+	}
+
+	// AddRoute panics with the same message
+	defer func() {
+		if recovered := recover(); recovered == nil {
+			t.Error("AddRoute of a conflicting route didn't panic")
+		}
+	}()
+	root.AddRoute("/users/:name", nil)
+}
+
+func TestPrecedence(t *testing.T) {
+	root := newTree(t,
+		"/users/new",
+		"/users/:id",
+		"/users/:id/edit",
+		"/users/new/edit",
+		"/users/*rest",
+		"/users/:uid{int}/posts",
+		"/users/:id/posts",
+		"/src/*filepath",
+		"/src/main.go",
+		"/a/:x/c",
+		"/a/b/d",
+		"/static/*path",
+		"/static/css/:file",
+	)
+
+	checkLookups(t, root, []lookupTest{
+		// Static wins over param, param over catch-all
+		{"/users/new", "/users/new", nil},
+		{"/users/42", "/users/:id", Params{{"id", "42"}}},
+		{"/users/42/x/y", "/users/*rest", Params{{"rest", "/42/x/y"}}},
+
+		// Constrained params are tried first
+		{"/users/42/posts", "/users/:uid{int}/posts", Params{{"uid", "42"}}},
+		{"/users/bob/posts", "/users/:id/posts", Params{{"id", "bob"}}},
+
+		// Backtracking from a static branch into a param
+		{"/users/new/edit", "/users/new/edit", nil},
+		{"/users/42/edit", "/users/:id/edit", Params{{"id", "42"}}},
+		{"/users/new/posts", "/users/:id/posts", Params{{"id", "new"}}},
+		{"/a/b/c", "/a/:x/c", Params{{"x", "b"}}},
+		{"/a/b/d", "/a/b/d", nil},
+
+		// Backtracking from a static branch or param into a catch-all
+		{"/users/new/other", "/users/*rest", Params{{"rest", "/new/other"}}},
+		{"/src/main.go", "/src/main.go", nil},
+		{"/src/main.c", "/src/*filepath", Params{{"filepath", "/main.c"}}},
+		{"/static/css/site.css", "/static/css/:file", Params{{"file", "site.css"}}},
+		{"/static/css/a/b.css", "/static/*path", Params{{"path", "/css/a/b.css"}}},
+		{"/static/", "/static/*path", Params{{"path", "/"}}},
+
+		{"/users", "", nil},
+		{"/a/b", "", nil},
+	})
+}
+
+// dump renders the structure of the tree below n, static children sorted
+func dump[T any](n *Node[T], indent string) string {
+	out := indent + n.path
+	if n.foldPath != foldCase(n.path) {
+		out += " (folded " + n.foldPath + ")"
+	}
+	if n.value != nil {
+		out += " => " + n.fullPath
+	}
+	out += "\n"
+
+	children := slices.Clone(n.children)
+	slices.SortFunc(children, func(a, b *Node[T]) int { return strings.Compare(a.path, b.path) })
+	for _, child := range children {
+		out += dump(child, indent+"  ")
+	}
+	for _, child := range n.params {
+		out += dump(child, indent+"  ")
+	}
+	if n.catchAll != nil {
+		out += dump(n.catchAll, indent+"  ")
+	}
+	return out
+}
+
+var removeRoutes = []string{
+	"/",
+	"/users",
+	"/users/new",
+	"/users/:id",
+	"/users/:id/edit",
+	"/users/:id{int}/posts",
+	"/files/*path",
+	"/files/:name.:ext",
+	"/search",
+	"/support",
+	"/sup",
+	"/a/b/c",
+	"/a/bc",
+	"/ab",
+}
+
+func TestRemoveRoute(t *testing.T) {
+	for i, removed := range removeRoutes {
+		root := newTree(t, removeRoutes...)
+		next, value := root.RemoveRoute(removed)
+		if value == nil || *value != removed {
+			t.Errorf("RemoveRoute(%q) removed %v", removed, value)
+			continue
+		}
+
+		// The nodes left are pruned and merged as if the route was never added
+		rest := slices.Delete(slices.Clone(removeRoutes), i, i+1)
+		if got, want := dump(next, ""), dump(newTree(t, rest...), ""); got != want {
+			t.Errorf("tree after RemoveRoute(%q):\n%s\nwant:\n%s", removed, got, want)
+		}
+
+		var ps Params
+		if found := next.GetValue(removed, &ps); found != nil && *found == removed {
+			t.Errorf("GetValue(%q) still matches after RemoveRoute", removed)
+		}
+	}
+
+	// Removing every route leaves an empty tree
+	root := newTree(t, removeRoutes...)
+	for _, route := range removeRoutes {
+		root, _ = root.RemoveRoute(route)
+	}
+	if got := dump(root, ""); got != "\n" {
+		t.Errorf("tree after removing all routes:\n%s", got)
+	}
+}
+
+func TestRemoveRouteMissing(t *testing.T) {
+	root := newTree(t, "/users/:id", "/files/*path", "/about")
+	for _, path := range []string{"/users", "/users/:name", "/files/*rest", "/abo", "/about/", "/x", "bad"} {
+		next, value := root.RemoveRoute(path)
+		if next != root || value != nil {
+			t.Errorf("RemoveRoute(%q) = %p, %v, want the same tree and nil", path, next, value)
+		}
+	}
+}
+
+func TestSnapshotsUnchanged(t *testing.T) {
+	root := newTree(t, removeRoutes...)
+	before := dump(root, "")
+
+	// Build and shrink new trees from the snapshot
+	next := root
+	for _, route := range []string{"/users/:id/delete", "/su", "/search/:q", "/files/:name", "/abc", "/a/b"} {
+		route := route
+		var err error
+		if next, err = next.InsertRoute(route, &route); err != nil {
+			t.Fatalf("InsertRoute(%q): %v", route, err)
+		}
+	}
+	for _, route := range removeRoutes {
+		next, _ = next.RemoveRoute(route)
+	}
+
+	if after := dump(root, ""); after != before {
+		t.Errorf("snapshot changed by later InsertRoute/RemoveRoute:\n%s\nwant:\n%s", after, before)
+	}
+	checkLookups(t, root, []lookupTest{
+		{"/users/42/edit", "/users/:id/edit", Params{{"id", "42"}}},
+		{"/users/42/delete", "", nil},
+		{"/sup", "/sup", nil},
+		{"/su", "", nil},
+	})
+	checkLookups(t, next, []lookupTest{
+		{"/users/42/delete", "/users/:id/delete", Params{{"id", "42"}}},
+		{"/users/42/edit", "", nil},
+		{"/su", "/su", nil},
+		{"/sup", "", nil},
+	})
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	root := newTree(t, "/users/:id/edit", "/Users/admin", "/café/menu")
+
+	tests := []struct {
+		path  string
+		route string
+		fixed string
+	}{
+		{"/USERS/42/EDIT", "/users/:id/edit", "/users/42/edit"},
+		{"/users/admin", "/Users/admin", "/Users/admin"},
+		{"/CAFÉ/MENU", "/café/menu", "/café/menu"},
+		{"/users/42/edit/", "", "/users/42/edit"},
+	}
+	for _, tt := range tests {
+		var ps Params
+		value := root.GetValueCaseInsensitive(tt.path, &ps)
+		route := ""
+		if value != nil {
+			route = *value
+		}
+		if route != tt.route {
+			t.Errorf("GetValueCaseInsensitive(%q) matched %q, want %q", tt.path, route, tt.route)
+		}
+		if fixed, _ := root.FindCaseInsensitivePath(tt.path, true); fixed != tt.fixed {
+			t.Errorf("FindCaseInsensitivePath(%q) = %q, want %q", tt.path, fixed, tt.fixed)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package drift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newBenchEngine returns an engine with routes of every kind registered
+func newBenchEngine() *Engine {
+	engine := newTestEngine()
+	h := func(c *Context) {}
+	engine.Get("/", h)
+	engine.Get("/users", h)
+	engine.Get("/users/:id", h)
+	engine.Get("/users/:id/repos", h)
+	engine.Get("/repos/:owner/:repo", h)
+	engine.Get("/repos/:owner/:repo/issues/:number", h)
+	engine.Get("/files/:name.:ext", h)
+	engine.Get("/static/*filepath", h)
+	engine.Post("/users", h)
+	return engine
+}
+
+// benchmarkRoute serves GET requests for path
+func benchmarkRoute(b *testing.B, path string) {
+	engine := newBenchEngine()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkStaticRoute(b *testing.B) {
+	benchmarkRoute(b, "/users")
+}
+
+func BenchmarkParamRoute(b *testing.B) {
+	benchmarkRoute(b, "/users/42")
+}
+
+func BenchmarkParamsRoute(b *testing.B) {
+	benchmarkRoute(b, "/repos/m1z23r/drift/issues/42")
+}
+
+func BenchmarkParamSuffixRoute(b *testing.B) {
+	benchmarkRoute(b, "/files/app.min.js")
+}
+
+func BenchmarkCatchAllRoute(b *testing.B) {
+	benchmarkRoute(b, "/static/css/site.css")
+}
+
+func TestZeroAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool does not reuse contexts reliably with the race detector")
+	}
+	engine := newBenchEngine()
+	w := httptest.NewRecorder()
+	for _, path := range []string{"/users", "/users/42", "/repos/m1z23r/drift/issues/42", "/files/app.min.js", "/static/css/site.css"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if allocs := testing.AllocsPerRun(100, func() { engine.ServeHTTP(w, req) }); allocs != 0 {
+			t.Errorf("GET %s: %v allocs, want 0", path, allocs)
+		}
+	}
+}
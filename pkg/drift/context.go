@@ -1,6 +1,7 @@
 package drift
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,41 +11,54 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/m1z23r/drift/internal/router"
 )
 
 // Context represents the context of the current HTTP request
 type Context struct {
 	Request  *http.Request
 	Response http.ResponseWriter
-	Params   map[string]string // URL parameters (:id, etc.)
-	Query    url.Values        // Query string parameters
+	Query    url.Values // Query string parameters, nil if there are none
+	Params   Params     // URL parameters (:id, etc.)
 
 	// Middleware chain management
 	handlers []HandlerFunc
 	index    int8
+	route    *Route // matched route, nil if none
 
-	// Context data storage (like gin's Set/Get)
-	mu     sync.RWMutex
-	store  map[string]any
+	// Context data storage (like gin's Set/Get), allocated on first use
+	mu      sync.RWMutex
+	store   map[string]any
 	aborted bool
 
 	// Response status
-	statusCode int
+	written bool // the status was sent by one of the response helpers
+
+	// Wraps Response to track the status and size actually written
+	writer responseWriter
+
+	engine *Engine
 }
 
 // HandlerFunc defines the handler function type
 type HandlerFunc func(*Context)
 
+// Param is a URL parameter
+type Param = router.Param
+
+// Params are the URL parameters of the matched route.
+// Use Get or ByName to look up a parameter. The slice is reused for the
+// next request, so copy it to keep it after the handler returns.
+type Params = router.Params
+
 // newContext creates a new Context instance
 func newContext(w http.ResponseWriter, r *http.Request) *Context {
 	return &Context{
-		Request:    r,
-		Response:   w,
-		Params:     make(map[string]string),
-		Query:      r.URL.Query(),
-		store:      make(map[string]any),
-		index:      -1,
-		statusCode: http.StatusOK,
+		Request:  r,
+		Response: w,
+		Query:    r.URL.Query(),
+		index:    -1,
 	}
 }
 
@@ -86,6 +100,9 @@ func (c *Context) IsAborted() bool {
 func (c *Context) Set(key string, value any) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.store == nil {
+		c.store = make(map[string]any)
+	}
 	c.store[key] = value
 }
 
@@ -137,7 +154,7 @@ func (c *Context) GetBool(key string) bool {
 
 // Param returns the value of the URL parameter
 func (c *Context) Param(key string) string {
-	return c.Params[key]
+	return c.Params.ByName(key)
 }
 
 // QueryParam returns the query string parameter
@@ -155,8 +172,7 @@ func (c *Context) DefaultQuery(key, defaultValue string) string {
 
 // Status sets the HTTP status code
 func (c *Context) Status(code int) {
-	c.statusCode = code
-	c.Response.WriteHeader(code)
+	c.writeHeader(code)
 }
 
 // Header sets a response header
@@ -172,8 +188,7 @@ func (c *Context) GetHeader(key string) string {
 // JSON sends a JSON response
 func (c *Context) JSON(code int, data any) error {
 	c.Header("Content-Type", "application/json")
-	c.statusCode = code
-	c.Response.WriteHeader(code)
+	c.writeHeader(code)
 	encoder := json.NewEncoder(c.Response)
 	return encoder.Encode(data)
 }
@@ -181,8 +196,7 @@ func (c *Context) JSON(code int, data any) error {
 // String sends a plain text response
 func (c *Context) String(code int, format string, values ...any) error {
 	c.Header("Content-Type", "text/plain")
-	c.statusCode = code
-	c.Response.WriteHeader(code)
+	c.writeHeader(code)
 	if len(values) > 0 {
 		_, err := fmt.Fprintf(c.Response, format, values...)
 		return err
@@ -194,8 +208,7 @@ func (c *Context) String(code int, format string, values ...any) error {
 // HTML sends an HTML response
 func (c *Context) HTML(code int, html string) error {
 	c.Header("Content-Type", "text/html; charset=utf-8")
-	c.statusCode = code
-	c.Response.WriteHeader(code)
+	c.writeHeader(code)
 	_, err := c.Response.Write([]byte(html))
 	return err
 }
@@ -203,8 +216,7 @@ func (c *Context) HTML(code int, html string) error {
 // Data writes raw bytes to the response
 func (c *Context) Data(code int, contentType string, data []byte) error {
 	c.Header("Content-Type", contentType)
-	c.statusCode = code
-	c.Response.WriteHeader(code)
+	c.writeHeader(code)
 	_, err := c.Response.Write(data)
 	return err
 }
@@ -214,9 +226,23 @@ func (c *Context) Redirect(code int, location string) {
 	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
 		code = http.StatusFound
 	}
+	c.written = true
 	http.Redirect(c.Response, c.Request, location, code)
 }
 
+// Written reports whether the response status has been sent, by the
+// Context helpers or directly through c.Response, after which a different
+// response can no longer be written
+func (c *Context) Written() bool {
+	return c.written || c.writer.status != 0
+}
+
+// writeHeader sends the status code and marks the response as written
+func (c *Context) writeHeader(code int) {
+	c.written = true
+	c.Response.WriteHeader(code)
+}
+
 // BindJSON binds the request body to a struct using JSON
 func (c *Context) BindJSON(obj any) error {
 	decoder := json.NewDecoder(c.Request.Body)
@@ -303,6 +329,15 @@ func (c *Context) ClientIP() string {
 	return c.Request.RemoteAddr
 }
 
+// ClientCertificate returns the certificate presented by the TLS client,
+// or nil if the request was not made over TLS with a client certificate
+func (c *Context) ClientCertificate() *x509.Certificate {
+	if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return c.Request.TLS.PeerCertificates[0]
+}
+
 // Method returns the HTTP method
 func (c *Context) Method() string {
 	return c.Request.Method
@@ -313,21 +348,30 @@ func (c *Context) Path() string {
 	return c.Request.URL.Path
 }
 
+// Route returns the matched route, or nil when no route matched
+// (e.g. in global middleware running for a 404)
+func (c *Context) Route() *Route {
+	return c.route
+}
+
 // FullPath returns the matched route full path
 func (c *Context) FullPath() string {
-	// This will be set during routing
-	if path, ok := c.Get("_fullPath"); ok {
-		return path.(string)
+	if c.route != nil {
+		return c.route.Path
 	}
 	return c.Request.URL.Path
 }
 
+// URLFor builds the URL of a named route, see Engine.URL
+func (c *Context) URLFor(name string, params ...any) (string, error) {
+	return c.engine.URL(name, params...)
+}
+
 // Stream writes data from an io.Reader to the response
 // This allows streaming large files without loading them into memory
 func (c *Context) Stream(code int, contentType string, reader io.Reader) error {
 	c.Header("Content-Type", contentType)
-	c.statusCode = code
-	c.Response.WriteHeader(code)
+	c.writeHeader(code)
 	_, err := io.Copy(c.Response, reader)
 	return err
 }
@@ -353,8 +397,7 @@ func (c *Context) File(filepath string) error {
 	// Set headers
 	c.Header("Content-Type", contentType)
 	c.Header("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-	c.statusCode = http.StatusOK
-	c.Response.WriteHeader(http.StatusOK)
+	c.writeHeader(http.StatusOK)
 
 	// Stream the file
 	_, err = io.Copy(c.Response, file)
@@ -387,8 +430,7 @@ func (c *Context) FileAttachment(filepath, filename string) error {
 	c.Header("Content-Type", contentType)
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
 	c.Header("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-	c.statusCode = http.StatusOK
-	c.Response.WriteHeader(http.StatusOK)
+	c.writeHeader(http.StatusOK)
 
 	// Stream the file
 	_, err = io.Copy(c.Response, file)
@@ -399,8 +441,7 @@ func (c *Context) FileAttachment(filepath, filename string) error {
 // This is useful for streaming data from any source (databases, APIs, etc.)
 func (c *Context) StreamReader(reader io.Reader, contentType string) error {
 	c.Header("Content-Type", contentType)
-	c.statusCode = http.StatusOK
-	c.Response.WriteHeader(http.StatusOK)
+	c.writeHeader(http.StatusOK)
 	_, err := io.Copy(c.Response, reader)
 	return err
 }
@@ -410,8 +451,7 @@ func (c *Context) StreamReader(reader io.Reader, contentType string) error {
 func (c *Context) StreamBytes(code int, contentType string, data []byte) error {
 	c.Header("Content-Type", contentType)
 	c.Header("Content-Length", fmt.Sprintf("%d", len(data)))
-	c.statusCode = code
-	c.Response.WriteHeader(code)
+	c.writeHeader(code)
 	_, err := c.Response.Write(data)
 	return err
 }
@@ -420,6 +460,7 @@ func (c *Context) StreamBytes(code int, contentType string, data []byte) error {
 type SSEWriter struct {
 	ctx    *Context
 	writer http.ResponseWriter
+	done   <-chan struct{}
 }
 
 // SSE initializes Server-Sent Events for this response
@@ -431,18 +472,28 @@ func (c *Context) SSE() *SSEWriter {
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no") // Disable buffering in nginx
 
-	c.statusCode = http.StatusOK
-	c.Response.WriteHeader(http.StatusOK)
+	c.writeHeader(http.StatusOK)
 
 	// Flush if the writer supports it
 	if flusher, ok := c.Response.(http.Flusher); ok {
 		flusher.Flush()
 	}
 
-	return &SSEWriter{
+	sse := &SSEWriter{
 		ctx:    c,
 		writer: c.Response,
 	}
+	if c.engine != nil {
+		sse.done = c.engine.Done()
+	}
+	return sse
+}
+
+// Done returns a channel that is closed when the server starts shutting down.
+// Long-running streams should select on it, send a final event and return
+// so the graceful shutdown can complete.
+func (s *SSEWriter) Done() <-chan struct{} {
+	return s.done
 }
 
 // Send sends an SSE event with optional event type and ID
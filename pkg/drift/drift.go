@@ -1,9 +1,15 @@
 package drift
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
+	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/m1z23r/drift/internal/router"
@@ -20,9 +26,76 @@ const (
 // Engine is the main framework instance
 type Engine struct {
 	RouterGroup
-	pool  sync.Pool
-	trees map[string]*router.Node // method -> radix tree
-	mode  Mode
+
+	// HandleHEAD serves HEAD requests with the GET handlers when no HEAD
+	// route is registered. The body is discarded, headers are kept.
+	HandleHEAD bool
+
+	// HandleOPTIONS answers OPTIONS requests with an Allow header when no
+	// OPTIONS route is registered.
+	HandleOPTIONS bool
+
+	// RedirectTrailingSlash redirects /foo/ to /foo (or /foo to /foo/) when
+	// only the other form is registered. GET and HEAD requests get a 301,
+	// other methods a 308.
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath cleans the path (removing // and ..) and looks it up
+	// case-insensitively when no route matches, redirecting to the
+	// registered form if one is found.
+	RedirectFixedPath bool
+
+	// UseRawPath matches routes against the escaped path (URL.RawPath)
+	// when it is available, so an encoded slash (%2F) stays inside a param
+	// instead of splitting the segment. Param values are unescaped after
+	// matching.
+	UseRawPath bool
+
+	// CaseInsensitive matches the static parts of routes case-insensitively,
+	// so /Users/42 matches /users/:id. Exact matches are preferred, and
+	// param values keep the case of the request.
+	CaseInsensitive bool
+
+	// CollectRouteErrors records invalid and conflicting routes instead of
+	// panicking when they are registered. The routes are skipped and the
+	// errors reported together by Validate and the Run methods.
+	CollectRouteErrors bool
+
+	// ErrorHandler turns errors returned by handlers wrapped with WrapE
+	// into responses. The default is DefaultErrorHandler.
+	ErrorHandler func(c *Context, err error)
+
+	pool sync.Pool
+	mode Mode
+
+	// Registered routes. Requests read the current table without locking;
+	// changes copy it and swap in the new table while holding routesMu.
+	routes   atomic.Pointer[routeTable]
+	routesMu sync.RWMutex
+
+	// Named routes for reverse URL generation, guarded by routesMu
+	namedRoutes map[string]*Route
+
+	// Registration errors recorded with CollectRouteErrors, guarded by routesMu
+	routeErrors RouteErrors
+
+	// Lifecycle hooks
+	hooks hooks
+
+	// Handlers for unmatched requests
+	noRoute     []HandlerFunc
+	noMethod    []HandlerFunc
+	allNoRoute  []HandlerFunc // global middleware + noRoute
+	allNoMethod []HandlerFunc // global middleware + noMethod
+	allOptions  []HandlerFunc // global middleware + automatic OPTIONS handler
+
+	// Server lifecycle
+	serverConfig    ServerConfig
+	mu              sync.Mutex
+	servers         map[*http.Server]struct{}
+	shutdown        chan struct{}
+	shutdownOnce    sync.Once
+	shutdownTimeout time.Duration
 }
 
 // New creates a new Engine instance in debug mode
@@ -33,13 +106,28 @@ func New() *Engine {
 			basePath: "/",
 			engine:   nil,
 		},
-		trees: make(map[string]*router.Node),
-		mode:  DebugMode,
+		HandleHEAD:            true,
+		HandleOPTIONS:         true,
+		RedirectTrailingSlash: true,
+		RedirectFixedPath:     false,
+		UseRawPath:            false,
+		CaseInsensitive:       false,
+		CollectRouteErrors:    false,
+		ErrorHandler:          DefaultErrorHandler,
+		namedRoutes:           make(map[string]*Route),
+		mode:                  DebugMode,
+		servers:               make(map[*http.Server]struct{}),
+		shutdown:              make(chan struct{}),
+		shutdownTimeout:       DefaultShutdownTimeout,
 	}
 	engine.RouterGroup.engine = engine
+	engine.routes.Store(&routeTable{trees: make(methodTrees)})
 	engine.pool.New = func() any {
-		return &Context{}
+		return &Context{engine: engine}
 	}
+	engine.rebuild404Handlers()
+	engine.rebuild405Handlers()
+	engine.rebuildOptionsHandlers()
 	return engine
 }
 
@@ -65,28 +153,49 @@ func (engine *Engine) IsDebug() bool {
 	return engine.mode == DebugMode
 }
 
-// addRoute adds a route to the engine
-func (engine *Engine) addRoute(method, path string, handlers []HandlerFunc) {
-	if path[0] != '/' {
-		panic("path must begin with '/'")
-	}
+// Use adds global middleware to the engine.
+// Global middleware also runs for the NoRoute and NoMethod handlers.
+func (engine *Engine) Use(middleware ...HandlerFunc) {
+	engine.RouterGroup.Use(middleware...)
+	engine.rebuild404Handlers()
+	engine.rebuild405Handlers()
+	engine.rebuildOptionsHandlers()
+}
+
+// methodTrees maps an HTTP method to its radix tree
+type methodTrees map[string]*router.Node[Route]
 
-	root := engine.trees[method]
-	if root == nil {
-		root = router.NewNode()
-		engine.trees[method] = root
+// addRoute adds a route to the engine, for the route's host or all hosts
+func (engine *Engine) addRoute(route *Route) {
+	// Parse once; the pattern is kept to build URLs
+	pattern, err := router.ParsePattern(route.Path)
+	if err != nil {
+		engine.routeError(newRouteError(route, err))
+		return
 	}
+	route.pattern = pattern
 
-	// Convert HandlerFunc to router.HandlerFunc (interface{})
-	routerHandlers := make([]router.HandlerFunc, len(handlers))
-	for i, h := range handlers {
-		routerHandlers[i] = h
+	engine.updateRoutes(func(table *routeTable) {
+		trees := table.methodTrees(route.Host)
+		if trees == nil {
+			err = errors.New("host '" + route.Host + "' is not registered")
+			return
+		}
+		root := trees[route.Method]
+		if root == nil {
+			root = router.NewNode[Route]()
+		}
+		trees[route.Method], err = root.InsertPattern(pattern, route)
+		route.registered = err == nil
+	})
+	if err != nil {
+		engine.routeError(newRouteError(route, err))
+		return
 	}
-	root.AddRoute(path, routerHandlers)
 
 	// Log route registration in debug mode
 	if engine.IsDebug() {
-		log.Printf("[DRIFT] %-7s %s", method, path)
+		log.Printf("[DRIFT] %-7s %s%s", route.Method, route.Host, route.Path)
 	}
 }
 
@@ -95,89 +204,360 @@ func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c := engine.pool.Get().(*Context)
 	c.Response = w
 	c.Request = req
-	c.Params = make(map[string]string)
-	c.Query = req.URL.Query()
-	c.store = make(map[string]any)
+	c.Params = c.Params[:0]
+	c.Query = nil
+	if req.URL.RawQuery != "" {
+		// Requests without a query string don't allocate
+		c.Query = req.URL.Query()
+	}
+	c.store = nil
+	c.route = nil
 	c.index = -1
 	c.aborted = false
-	c.statusCode = http.StatusOK
+	c.written = false
+
+	// Track the response for Written and the OnResponse hooks
+	c.writer.reset(w)
+	c.Response = &c.writer
+	trackResponse := len(engine.hooks.response) > 0
 
 	// Log request in debug mode
 	var start time.Time
-	if engine.IsDebug() {
+	if engine.IsDebug() || trackResponse {
 		start = time.Now()
 	}
 
-	engine.handleRequest(c)
+	engine.serveRequest(c)
+
+	if trackResponse {
+		engine.runResponseHooks(c, start)
+	}
 
 	// Log response in debug mode
 	if engine.IsDebug() {
 		duration := time.Since(start)
-		log.Printf("[DRIFT] %s %s - %d - %v", req.Method, req.URL.Path, c.statusCode, duration)
+		log.Printf("[DRIFT] %s %s - %d - %v", req.Method, req.URL.Path, c.writer.Status(), duration)
 	}
 
+	c.writer.reset(nil)
 	engine.pool.Put(c)
 }
 
+// serveRequest runs the request hooks and handles the request. A panic that
+// no middleware recovered is logged, reported to the OnPanic hooks and
+// answered with a 500, so the response is still finished normally.
+// Deliberate aborts with http.ErrAbortHandler continue to net/http.
+func (engine *Engine) serveRequest(c *Context) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		if recovered == http.ErrAbortHandler {
+			panic(recovered)
+		}
+
+		log.Printf("[DRIFT] panic serving %s %s: %v\n%s", c.Request.Method, c.Request.URL.Path, recovered, debug.Stack())
+		engine.runPanicHooks(c, recovered)
+		if !c.Written() {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: http.StatusText(http.StatusInternalServerError),
+			})
+		}
+	}()
+
+	engine.runRequestHooks(c)
+	engine.handleRequest(c)
+}
+
 // handleRequest handles the HTTP request
 func (engine *Engine) handleRequest(c *Context) {
 	httpMethod := c.Request.Method
 	path := c.Request.URL.Path
+	if path == "" {
+		// Absolute-form requests like "GET http://example.com" have no path
+		path = "/"
+	}
+	unescape := false
+	if engine.UseRawPath && c.Request.URL.RawPath != "" {
+		if rawPath, ok := matchRawPath(c.Request.URL.RawPath); ok {
+			path = rawPath
+			unescape = true
+		}
+	}
+
+	// Select the routes of the requested host
+	table := engine.routes.Load()
+	trees := table.trees
+	if len(table.hosts) > 0 {
+		if host := table.matchHost(c.Request.Host, &c.Params); host != nil {
+			trees = host.trees
+		}
+	}
 
 	// Find route
-	if root := engine.trees[httpMethod]; root != nil {
-		routerHandlers, params, fullPath := root.GetValue(path)
-		if routerHandlers != nil {
-			// Convert router.HandlerFunc back to HandlerFunc
-			handlers := make([]HandlerFunc, len(routerHandlers))
-			for i, h := range routerHandlers {
-				handlers[i] = h.(HandlerFunc)
+	if root := trees[httpMethod]; root != nil {
+		if route := engine.getRoute(root, path, &c.Params, unescape); route != nil {
+			engine.runRoute(c, route)
+			return
+		}
+	}
+
+	// Serve HEAD from the GET route with the body discarded
+	if httpMethod == http.MethodHead && engine.HandleHEAD {
+		if root := trees[http.MethodGet]; root != nil {
+			if route := engine.getRoute(root, path, &c.Params, unescape); route != nil {
+				hw := &headResponseWriter{ResponseWriter: c.Response}
+				c.Response = hw
+				engine.runRoute(c, route)
+				hw.finish()
+				return
 			}
-			c.handlers = handlers
-			c.Params = params
-			c.Set("_fullPath", fullPath)
+		}
+	}
+
+	// Answer OPTIONS with the methods registered for the path
+	if httpMethod == http.MethodOptions && engine.HandleOPTIONS {
+		if allowed := engine.allowedMethods(trees, path, ""); len(allowed) > 0 {
+			c.Header("Allow", strings.Join(allowed, ", "))
+			c.handlers = engine.allOptions
 			c.Next()
 			return
 		}
 	}
 
-	// No route found - include global middleware with 404 handler
-	notFoundHandler := func(c *Context) {
-		c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Not Found",
-		})
+	// Redirect to the registered form of the path
+	if httpMethod != http.MethodConnect && path != "/" {
+		if root := engine.redirectTree(trees, httpMethod); root != nil {
+			if engine.RedirectTrailingSlash && root.TrailingSlashMatch(path, engine.CaseInsensitive) {
+				if path[len(path)-1] == '/' {
+					engine.redirectRequest(c, path[:len(path)-1], unescape)
+				} else {
+					engine.redirectRequest(c, path+"/", unescape)
+				}
+				return
+			}
+
+			if engine.RedirectFixedPath {
+				fixedPath, found := root.FindCaseInsensitivePath(router.CleanPath(path), engine.RedirectTrailingSlash)
+				if found && fixedPath != path {
+					engine.redirectRequest(c, fixedPath, unescape)
+					return
+				}
+			}
+		}
+	}
+
+	// Path exists for other methods - 405 Method Not Allowed
+	if allowed := engine.allowedMethods(trees, path, httpMethod); len(allowed) > 0 {
+		c.Header("Allow", strings.Join(allowed, ", "))
+		c.handlers = engine.allNoMethod
+		c.Next()
+		return
 	}
 
-	// Combine global middleware with 404 handler
-	globalHandlers := engine.RouterGroup.handlers
-	c.handlers = make([]HandlerFunc, len(globalHandlers)+1)
-	copy(c.handlers, globalHandlers)
-	c.handlers[len(globalHandlers)] = notFoundHandler
+	// No route found
+	c.handlers = engine.allNoRoute
 	c.Next()
 }
 
-// Run starts the HTTP server
-func (engine *Engine) Run(addr string) error {
+// getRoute looks up the route for path in root and appends its params to ps.
+// If unescape is true, path is a raw path and the param values are unescaped.
+func (engine *Engine) getRoute(root *router.Node[Route], path string, ps *Params, unescape bool) *Route {
+	mark := len(*ps)
+	route := root.GetValue(path, ps)
+	if route == nil && engine.CaseInsensitive {
+		route = root.GetValueCaseInsensitive(path, ps)
+	}
+
+	if route != nil && unescape {
+		for i := mark; i < len(*ps); i++ {
+			p := &(*ps)[i]
+			if strings.IndexByte(p.Value, '%') >= 0 {
+				if value, err := url.PathUnescape(p.Value); err == nil {
+					p.Value = value
+				}
+			}
+		}
+	}
+	return route
+}
+
+// matchRawPath decodes the escapes of a raw URL path except %2F and %25, so
+// encoded slashes stay inside params and param values can be unescaped
+// exactly once after matching. It reports false for an invalid escape.
+func matchRawPath(rawPath string) (string, bool) {
+	if strings.IndexByte(rawPath, '%') < 0 {
+		return rawPath, true
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(rawPath))
+	for i := 0; i < len(rawPath); i++ {
+		if rawPath[i] != '%' {
+			sb.WriteByte(rawPath[i])
+			continue
+		}
+		if i+2 >= len(rawPath) || !isHex(rawPath[i+1]) || !isHex(rawPath[i+2]) {
+			return "", false
+		}
+		if b := unhex(rawPath[i+1])<<4 | unhex(rawPath[i+2]); b == '/' || b == '%' {
+			sb.WriteString(rawPath[i : i+3])
+		} else {
+			sb.WriteByte(b)
+		}
+		i += 2
+	}
+	return sb.String(), true
+}
+
+// isHex reports whether c is a hex digit
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// unhex returns the value of the hex digit c
+func unhex(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+// runRoute runs the handler chain of a matched route
+func (engine *Engine) runRoute(c *Context, route *Route) {
+	c.route = route
+	c.handlers = route.handlers
+	engine.runRouteMatchedHooks(c)
+	c.Next()
+}
+
+// redirectTree returns the tree used to look up redirects for method
+func (engine *Engine) redirectTree(trees methodTrees, method string) *router.Node[Route] {
+	if root := trees[method]; root != nil {
+		return root
+	}
+	if method == http.MethodHead && engine.HandleHEAD {
+		return trees[http.MethodGet]
+	}
+	return nil
+}
+
+// redirectRequest permanently redirects the request to path, keeping the query string.
+// GET and HEAD requests get a 301, other methods a 308 so the body is resent.
+// raw reports whether path is based on the raw path and keeps its escapes.
+func (engine *Engine) redirectRequest(c *Context, path string, raw bool) {
+	code := http.StatusMovedPermanently
+	if method := c.Request.Method; method != http.MethodGet && method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+
+	// A mounted engine redirects below its mount prefix
+	path = mountPrefix(c.Request) + path
+
+	u := &url.URL{Path: path, RawQuery: c.Request.URL.RawQuery}
+	if raw {
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			u.Path, u.RawPath = unescaped, path
+		}
+	}
+	location := u.String()
 	if engine.IsDebug() {
-		log.Printf("[DRIFT] Starting server in %s mode on %s", engine.mode, addr)
-		log.Printf("[DRIFT] Use engine.SetMode(drift.ReleaseMode) to disable debug logs")
+		log.Printf("[DRIFT] redirecting request %d: %s --> %s", code, c.Request.URL.Path, location)
 	}
-	return http.ListenAndServe(addr, engine)
+	c.Redirect(code, location)
 }
 
-// RunTLS starts the HTTPS server
-func (engine *Engine) RunTLS(addr, certFile, keyFile string) error {
-	return http.ListenAndServeTLS(addr, certFile, keyFile, engine)
+// allowedMethods returns the sorted methods with a route matching path, except skip.
+// HEAD and OPTIONS are included when they are answered automatically.
+func (engine *Engine) allowedMethods(trees methodTrees, path, skip string) []string {
+	var allowed []string
+	var ps Params
+	hasGet, hasHead, hasOptions := false, false, false
+	for method, root := range trees {
+		if engine.getRoute(root, path, &ps, false) != nil {
+			switch method {
+			case http.MethodGet:
+				hasGet = true
+			case http.MethodHead:
+				hasHead = true
+			case http.MethodOptions:
+				hasOptions = true
+			}
+			if method != skip {
+				allowed = append(allowed, method)
+			}
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	if engine.HandleHEAD && hasGet && !hasHead && skip != http.MethodHead {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if engine.HandleOPTIONS && !hasOptions && skip != http.MethodOptions {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	sort.Strings(allowed)
+	return allowed
 }
 
-// NoRoute registers handlers for when no route is matched
+// NoRoute registers handlers for when no route is matched.
+// The handlers run after the global middleware; by default a JSON 404 is sent.
 func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
-	// This would require storing and using custom 404 handlers
-	// Simplified version - could be enhanced
+	engine.noRoute = handlers
+	engine.rebuild404Handlers()
 }
 
-// NoMethod registers handlers for when the method is not allowed
+// NoMethod registers handlers for when the path matches but the method is not allowed.
+// The Allow header is set before the handlers run; by default a JSON 405 is sent.
 func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
-	// This would require storing and using custom 405 handlers
-	// Simplified version - could be enhanced
+	engine.noMethod = handlers
+	engine.rebuild405Handlers()
+}
+
+// rebuild404Handlers combines the global middleware with the 404 handlers
+func (engine *Engine) rebuild404Handlers() {
+	handlers := engine.noRoute
+	if len(handlers) == 0 {
+		handlers = []HandlerFunc{defaultNoRoute}
+	}
+	engine.allNoRoute = engine.combineHandlers(handlers)
+}
+
+// rebuild405Handlers combines the global middleware with the 405 handlers
+func (engine *Engine) rebuild405Handlers() {
+	handlers := engine.noMethod
+	if len(handlers) == 0 {
+		handlers = []HandlerFunc{defaultNoMethod}
+	}
+	engine.allNoMethod = engine.combineHandlers(handlers)
+}
+
+// rebuildOptionsHandlers combines the global middleware with the automatic OPTIONS handler
+func (engine *Engine) rebuildOptionsHandlers() {
+	engine.allOptions = engine.combineHandlers([]HandlerFunc{defaultOptions})
+}
+
+// defaultNoRoute is the default 404 handler
+func defaultNoRoute(c *Context) {
+	c.JSON(http.StatusNotFound, map[string]string{
+		"error": "Not Found",
+	})
+}
+
+// defaultNoMethod is the default 405 handler
+func defaultNoMethod(c *Context) {
+	c.JSON(http.StatusMethodNotAllowed, map[string]string{
+		"error": "Method Not Allowed",
+	})
+}
+
+// defaultOptions answers an OPTIONS request whose Allow header is already set
+func defaultOptions(c *Context) {
+	c.Status(http.StatusNoContent)
 }
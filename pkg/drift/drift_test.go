@@ -0,0 +1,37 @@
+package drift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestEngine returns an engine in release mode, so tests don't log
+func newTestEngine() *Engine {
+	engine := New()
+	engine.SetMode(ReleaseMode)
+	return engine
+}
+
+// serve sends a request to engine and returns the recorded response
+func serve(engine *Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestEmptyPath(t *testing.T) {
+	engine := newTestEngine()
+	engine.RedirectFixedPath = true
+	engine.Get("/", func(c *Context) { c.String(http.StatusOK, "home") })
+	engine.Get("/users/", func(c *Context) { c.String(http.StatusOK, "users") })
+
+	// An absolute-form request line like "GET http://example.com HTTP/1.1"
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.URL.Path = ""
+
+	w := serve(engine, req)
+	if w.Code != http.StatusOK || w.Body.String() != "home" {
+		t.Errorf("got %d %q, want 200 \"home\"", w.Code, w.Body.String())
+	}
+}
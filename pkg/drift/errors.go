@@ -1,6 +1,9 @@
 package drift
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+)
 
 // HTTPError represents a custom HTTP error
 type HTTPError struct {
@@ -21,6 +24,70 @@ func NewHTTPError(code int, message string) *HTTPError {
 	}
 }
 
+// WrapE wraps a handler that returns an error so it can be used as a drift
+// handler. A returned error is passed to the OnError hooks and then to
+// Engine.ErrorHandler.
+//
+//	app.Get("/users/:id", drift.WrapE(func(c *drift.Context) error {
+//		return c.JSON(http.StatusOK, user)
+//	}))
+func WrapE(h func(c *Context) error) HandlerFunc {
+	return nameWrapper(func(c *Context) {
+		if err := h(c); err != nil {
+			c.handleError(err)
+		}
+	}, nameOfFunction(h))
+}
+
+// DefaultErrorHandler is the default Engine.ErrorHandler. An *HTTPError,
+// also when wrapped, is sent with its code; other errors become a 500 whose
+// message is only shown outside release mode. If the response was already
+// written, the chain is only aborted.
+func DefaultErrorHandler(c *Context, err error) {
+	if c.Written() {
+		c.Abort()
+		return
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		message := httpErr.Message
+		if message == "" {
+			message = http.StatusText(httpErr.Code)
+		}
+		c.AbortWithStatusJSON(httpErr.Code, HTTPError{
+			Code:    httpErr.Code,
+			Message: message,
+		})
+		return
+	}
+
+	message := err.Error()
+	if c.engine == nil || c.engine.mode == ReleaseMode {
+		message = http.StatusText(http.StatusInternalServerError)
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, HTTPError{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+	})
+}
+
+// handleError passes an error returned by a handler to the OnError hooks
+// and the engine's ErrorHandler
+func (c *Context) handleError(err error) {
+	if c.engine == nil {
+		DefaultErrorHandler(c, err)
+		return
+	}
+
+	c.engine.runErrorHooks(c, err)
+	if c.engine.ErrorHandler != nil {
+		c.engine.ErrorHandler(c, err)
+	} else {
+		DefaultErrorHandler(c, err)
+	}
+}
+
 // Common HTTP error helpers
 
 // BadRequest returns a 400 Bad Request error
@@ -28,10 +95,7 @@ func (c *Context) BadRequest(message string) {
 	if message == "" {
 		message = "Bad Request"
 	}
-	c.AbortWithStatusJSON(http.StatusBadRequest, HTTPError{
-		Code:    http.StatusBadRequest,
-		Message: message,
-	})
+	c.abortWithError(http.StatusBadRequest, message)
 }
 
 // Unauthorized returns a 401 Unauthorized error
@@ -39,10 +103,7 @@ func (c *Context) Unauthorized(message string) {
 	if message == "" {
 		message = "Unauthorized"
 	}
-	c.AbortWithStatusJSON(http.StatusUnauthorized, HTTPError{
-		Code:    http.StatusUnauthorized,
-		Message: message,
-	})
+	c.abortWithError(http.StatusUnauthorized, message)
 }
 
 // Forbidden returns a 403 Forbidden error
@@ -50,10 +111,7 @@ func (c *Context) Forbidden(message string) {
 	if message == "" {
 		message = "Forbidden"
 	}
-	c.AbortWithStatusJSON(http.StatusForbidden, HTTPError{
-		Code:    http.StatusForbidden,
-		Message: message,
-	})
+	c.abortWithError(http.StatusForbidden, message)
 }
 
 // NotFound returns a 404 Not Found error
@@ -61,10 +119,7 @@ func (c *Context) NotFound(message string) {
 	if message == "" {
 		message = "Not Found"
 	}
-	c.AbortWithStatusJSON(http.StatusNotFound, HTTPError{
-		Code:    http.StatusNotFound,
-		Message: message,
-	})
+	c.abortWithError(http.StatusNotFound, message)
 }
 
 // MethodNotAllowed returns a 405 Method Not Allowed error
@@ -72,10 +127,7 @@ func (c *Context) MethodNotAllowed(message string) {
 	if message == "" {
 		message = "Method Not Allowed"
 	}
-	c.AbortWithStatusJSON(http.StatusMethodNotAllowed, HTTPError{
-		Code:    http.StatusMethodNotAllowed,
-		Message: message,
-	})
+	c.abortWithError(http.StatusMethodNotAllowed, message)
 }
 
 // Conflict returns a 409 Conflict error
@@ -83,10 +135,7 @@ func (c *Context) Conflict(message string) {
 	if message == "" {
 		message = "Conflict"
 	}
-	c.AbortWithStatusJSON(http.StatusConflict, HTTPError{
-		Code:    http.StatusConflict,
-		Message: message,
-	})
+	c.abortWithError(http.StatusConflict, message)
 }
 
 // UnprocessableEntity returns a 422 Unprocessable Entity error
@@ -94,10 +143,7 @@ func (c *Context) UnprocessableEntity(message string) {
 	if message == "" {
 		message = "Unprocessable Entity"
 	}
-	c.AbortWithStatusJSON(http.StatusUnprocessableEntity, HTTPError{
-		Code:    http.StatusUnprocessableEntity,
-		Message: message,
-	})
+	c.abortWithError(http.StatusUnprocessableEntity, message)
 }
 
 // TooManyRequests returns a 429 Too Many Requests error
@@ -105,10 +151,7 @@ func (c *Context) TooManyRequests(message string) {
 	if message == "" {
 		message = "Too Many Requests"
 	}
-	c.AbortWithStatusJSON(http.StatusTooManyRequests, HTTPError{
-		Code:    http.StatusTooManyRequests,
-		Message: message,
-	})
+	c.abortWithError(http.StatusTooManyRequests, message)
 }
 
 // InternalServerError returns a 500 Internal Server Error
@@ -116,10 +159,7 @@ func (c *Context) InternalServerError(message string) {
 	if message == "" {
 		message = "Internal Server Error"
 	}
-	c.AbortWithStatusJSON(http.StatusInternalServerError, HTTPError{
-		Code:    http.StatusInternalServerError,
-		Message: message,
-	})
+	c.abortWithError(http.StatusInternalServerError, message)
 }
 
 // NotImplemented returns a 501 Not Implemented error
@@ -127,10 +167,7 @@ func (c *Context) NotImplemented(message string) {
 	if message == "" {
 		message = "Not Implemented"
 	}
-	c.AbortWithStatusJSON(http.StatusNotImplemented, HTTPError{
-		Code:    http.StatusNotImplemented,
-		Message: message,
-	})
+	c.abortWithError(http.StatusNotImplemented, message)
 }
 
 // BadGateway returns a 502 Bad Gateway error
@@ -138,10 +175,7 @@ func (c *Context) BadGateway(message string) {
 	if message == "" {
 		message = "Bad Gateway"
 	}
-	c.AbortWithStatusJSON(http.StatusBadGateway, HTTPError{
-		Code:    http.StatusBadGateway,
-		Message: message,
-	})
+	c.abortWithError(http.StatusBadGateway, message)
 }
 
 // ServiceUnavailable returns a 503 Service Unavailable error
@@ -149,10 +183,7 @@ func (c *Context) ServiceUnavailable(message string) {
 	if message == "" {
 		message = "Service Unavailable"
 	}
-	c.AbortWithStatusJSON(http.StatusServiceUnavailable, HTTPError{
-		Code:    http.StatusServiceUnavailable,
-		Message: message,
-	})
+	c.abortWithError(http.StatusServiceUnavailable, message)
 }
 
 // GatewayTimeout returns a 504 Gateway Timeout error
@@ -160,10 +191,7 @@ func (c *Context) GatewayTimeout(message string) {
 	if message == "" {
 		message = "Gateway Timeout"
 	}
-	c.AbortWithStatusJSON(http.StatusGatewayTimeout, HTTPError{
-		Code:    http.StatusGatewayTimeout,
-		Message: message,
-	})
+	c.abortWithError(http.StatusGatewayTimeout, message)
 }
 
 // Error returns a custom HTTP error with the given status code and message
@@ -172,10 +200,7 @@ func (c *Context) Error(code int, message string) {
 	if message == "" {
 		message = http.StatusText(code)
 	}
-	c.AbortWithStatusJSON(code, HTTPError{
-		Code:    code,
-		Message: message,
-	})
+	c.abortWithError(code, message)
 }
 
 // ErrorWithData returns a custom HTTP error with the given status code and custom data
@@ -183,3 +208,15 @@ func (c *Context) Error(code int, message string) {
 func (c *Context) ErrorWithData(code int, data any) {
 	c.AbortWithStatusJSON(code, data)
 }
+
+// abortWithError reports the error to the OnError hooks and sends it as JSON
+func (c *Context) abortWithError(code int, message string) {
+	err := &HTTPError{
+		Code:    code,
+		Message: message,
+	}
+	if c.engine != nil {
+		c.engine.runErrorHooks(c, err)
+	}
+	c.AbortWithStatusJSON(code, err)
+}
@@ -0,0 +1,90 @@
+package drift
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrapE(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		err  error
+		code int
+		body string
+	}{
+		{ReleaseMode, NewHTTPError(http.StatusNotFound, "no user"), http.StatusNotFound, `{"code":404,"message":"no user"}`},
+		{ReleaseMode, fmt.Errorf("loading: %w", NewHTTPError(http.StatusConflict, "")), http.StatusConflict, `{"code":409,"message":"Conflict"}`},
+		{ReleaseMode, errors.New("db down"), http.StatusInternalServerError, `{"code":500,"message":"Internal Server Error"}`},
+		{DebugMode, errors.New("db down"), http.StatusInternalServerError, `{"code":500,"message":"db down"}`},
+	}
+	for _, tt := range tests {
+		engine := New()
+		engine.SetMode(tt.mode)
+		var hooked error
+		engine.OnError(func(c *Context, err error) { hooked = err })
+		engine.Get("/", WrapE(func(c *Context) error { return tt.err }))
+
+		w := serve(engine, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tt.code || w.Body.String() != tt.body+"\n" {
+			t.Errorf("%s %v: got %d %q, want %d %q", tt.mode, tt.err, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if hooked != tt.err {
+			t.Errorf("%s %v: OnError got %v", tt.mode, tt.err, hooked)
+		}
+	}
+}
+
+func TestWrapEWritten(t *testing.T) {
+	tests := []struct {
+		name  string
+		hooks bool
+		write func(c *Context)
+	}{
+		{"String", false, func(c *Context) { c.String(http.StatusOK, "partial") }},
+		{"Response.Write", false, func(c *Context) { c.Response.Write([]byte("partial")) }},
+		{"io.Copy", false, func(c *Context) { io.Copy(c.Response, strings.NewReader("partial")) }},
+		{"WrapH", false, WrapH(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("partial"))
+		}))},
+		{"Response.Write with hooks", true, func(c *Context) { c.Response.Write([]byte("partial")) }},
+	}
+	for _, tt := range tests {
+		engine := newTestEngine()
+		var hooked error
+		engine.OnError(func(c *Context, err error) { hooked = err })
+		if tt.hooks {
+			engine.OnResponse(func(c *Context, info ResponseInfo) {})
+		}
+		engine.Get("/", WrapE(func(c *Context) error {
+			tt.write(c)
+			return errors.New("boom")
+		}), func(c *Context) { t.Errorf("%s: the chain continued after an error", tt.name) })
+
+		w := serve(engine, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "partial" {
+			t.Errorf("%s: got %d %q, want 200 \"partial\"", tt.name, w.Code, w.Body.String())
+		}
+		if hooked == nil || hooked.Error() != "boom" {
+			t.Errorf("%s: OnError got %v, want boom", tt.name, hooked)
+		}
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	engine := newTestEngine()
+	engine.ErrorHandler = func(c *Context, err error) {
+		c.String(http.StatusTeapot, "custom: %v", err)
+	}
+	engine.Get("/", WrapE(func(c *Context) error { return errors.New("boom") }))
+
+	w := serve(engine, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTeapot || w.Body.String() != "custom: boom" {
+		t.Errorf("got %d %q, want 418 \"custom: boom\"", w.Code, w.Body.String())
+	}
+}
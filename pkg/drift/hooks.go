@@ -0,0 +1,100 @@
+package drift
+
+import "time"
+
+// ResponseInfo describes a finished response for the OnResponse hooks
+type ResponseInfo struct {
+	Status   int           // status code sent to the client
+	Size     int64         // number of body bytes written
+	Duration time.Duration // time spent serving the request
+}
+
+// hooks are the lifecycle callbacks registered on the engine
+type hooks struct {
+	request      []func(*Context)
+	routeMatched []func(*Context)
+	response     []func(*Context, ResponseInfo)
+	panic        []func(*Context, any)
+	err          []func(*Context, error)
+}
+
+// OnRequest registers hooks that run when a request arrives, before routing.
+// Like middleware, hooks must be registered before the server starts.
+func (engine *Engine) OnRequest(hooks ...func(c *Context)) {
+	engine.hooks.request = append(engine.hooks.request, hooks...)
+}
+
+// OnRouteMatched registers hooks that run when a route matched the request,
+// before its handlers. c.Route() and c.FullPath() are set.
+func (engine *Engine) OnRouteMatched(hooks ...func(c *Context)) {
+	engine.hooks.routeMatched = append(engine.hooks.routeMatched, hooks...)
+}
+
+// OnResponse registers hooks that run after the request was handled,
+// including requests answered with 404, 405 or a redirect. The status and
+// size are those actually written to the client.
+func (engine *Engine) OnResponse(hooks ...func(c *Context, info ResponseInfo)) {
+	engine.hooks.response = append(engine.hooks.response, hooks...)
+}
+
+// OnPanic registers hooks that run when a handler panics. Panics that no
+// middleware recovered are answered with a 500 afterwards; middleware.Recovery
+// reports the panics it recovers through Context.ReportPanic.
+func (engine *Engine) OnPanic(hooks ...func(c *Context, recovered any)) {
+	engine.hooks.panic = append(engine.hooks.panic, hooks...)
+}
+
+// OnError registers hooks that run when an error response is sent with
+// one of the HTTP error helpers, e.g. c.BadRequest or c.Error, and when a
+// handler returns an error
+func (engine *Engine) OnError(hooks ...func(c *Context, err error)) {
+	engine.hooks.err = append(engine.hooks.err, hooks...)
+}
+
+// runRequestHooks runs the OnRequest hooks
+func (engine *Engine) runRequestHooks(c *Context) {
+	for _, hook := range engine.hooks.request {
+		hook(c)
+	}
+}
+
+// runRouteMatchedHooks runs the OnRouteMatched hooks
+func (engine *Engine) runRouteMatchedHooks(c *Context) {
+	for _, hook := range engine.hooks.routeMatched {
+		hook(c)
+	}
+}
+
+// runResponseHooks runs the OnResponse hooks for the request started at start
+func (engine *Engine) runResponseHooks(c *Context, start time.Time) {
+	info := ResponseInfo{
+		Status:   c.writer.Status(),
+		Size:     c.writer.size,
+		Duration: time.Since(start),
+	}
+	for _, hook := range engine.hooks.response {
+		hook(c, info)
+	}
+}
+
+// runErrorHooks runs the OnError hooks
+func (engine *Engine) runErrorHooks(c *Context, err error) {
+	for _, hook := range engine.hooks.err {
+		hook(c, err)
+	}
+}
+
+// runPanicHooks runs the OnPanic hooks
+func (engine *Engine) runPanicHooks(c *Context, recovered any) {
+	for _, hook := range engine.hooks.panic {
+		hook(c, recovered)
+	}
+}
+
+// ReportPanic passes a panic recovered by middleware, e.g. middleware.Recovery,
+// to the OnPanic hooks
+func (c *Context) ReportPanic(recovered any) {
+	if c.engine != nil {
+		c.engine.runPanicHooks(c, recovered)
+	}
+}
@@ -0,0 +1,58 @@
+package drift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPanicHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler HandlerFunc
+		code    int
+		body    string
+	}{
+		{"unwritten", func(c *Context) { panic("boom") }, http.StatusInternalServerError, `{"code":500,"message":"Internal Server Error"}` + "\n"},
+		{"written", func(c *Context) {
+			c.String(http.StatusAccepted, "partial")
+			panic("boom")
+		}, http.StatusAccepted, "partial"},
+	}
+	for _, tt := range tests {
+		engine := newTestEngine()
+		var recovered any
+		var info ResponseInfo
+		engine.OnPanic(func(c *Context, r any) { recovered = r })
+		engine.OnResponse(func(c *Context, i ResponseInfo) { info = i })
+		engine.Get("/", tt.handler)
+
+		w := serve(engine, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if recovered != "boom" {
+			t.Errorf("%s: OnPanic got %v, want boom", tt.name, recovered)
+		}
+		if info.Status != tt.code {
+			t.Errorf("%s: OnResponse got status %d, want %d", tt.name, info.Status, tt.code)
+		}
+	}
+}
+
+func TestPanicAbortHandler(t *testing.T) {
+	engine := newTestEngine()
+	called := false
+	engine.OnPanic(func(c *Context, r any) { called = true })
+	engine.Get("/", func(c *Context) { panic(http.ErrAbortHandler) })
+
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", r)
+		}
+		if called {
+			t.Error("OnPanic ran for http.ErrAbortHandler")
+		}
+	}()
+	serve(engine, httptest.NewRequest(http.MethodGet, "/", nil))
+}
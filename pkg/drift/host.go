@@ -0,0 +1,180 @@
+package drift
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Host pattern kinds, in match order
+const (
+	hostExact    = iota // api.example.com
+	hostParam           // :tenant.example.com
+	hostWildcard        // *.example.com
+)
+
+// hostRouter holds the routes of one host pattern
+type hostRouter struct {
+	pattern  string
+	host     string // exact patterns only, without IPv6 brackets
+	labels   []string
+	kind     int
+	literals int // number of literal labels, more specific patterns match first
+	trees    methodTrees
+}
+
+// Host returns a router group whose routes only match requests for the given host.
+// A label of the pattern can be a literal, a :param that is available through
+// Context.Param, or a * wildcard; each matches exactly one label:
+//
+//	admin := app.Host("admin.example.com")
+//	tenant := app.Host(":tenant.example.com")
+//	any := app.Host("*.example.com")
+//	local := app.Host("[::1]")
+//
+// Exact hosts are matched first, then patterns with params, then wildcards.
+// Requests for other hosts use the routes registered on the engine.
+// An invalid pattern panics, or is recorded with CollectRouteErrors.
+func (engine *Engine) Host(pattern string, handlers ...HandlerFunc) *RouterGroup {
+	pattern = strings.ToLower(pattern)
+
+	if engine.routes.Load().findHost(pattern) == nil {
+		host, err := newHostRouter(pattern)
+		if err != nil {
+			engine.routeError(&RouteError{Host: pattern, Reason: err.Error()})
+		} else {
+			engine.updateRoutes(func(table *routeTable) {
+				if table.findHost(pattern) == nil {
+					table.addHost(host)
+				}
+			})
+		}
+	}
+
+	return &RouterGroup{
+		handlers: engine.combineHandlers(handlers),
+		basePath: "/",
+		engine:   engine,
+		host:     pattern,
+	}
+}
+
+// addHost adds a host router, keeping the hosts in match order
+func (table *routeTable) addHost(host *hostRouter) {
+	table.hosts = append(table.hosts, host)
+	sort.SliceStable(table.hosts, func(i, j int) bool {
+		a, b := table.hosts[i], table.hosts[j]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		return a.literals > b.literals
+	})
+}
+
+// findHost returns the router registered for pattern
+func (table *routeTable) findHost(pattern string) *hostRouter {
+	for _, host := range table.hosts {
+		if host.pattern == pattern {
+			return host
+		}
+	}
+	return nil
+}
+
+// matchHost returns the router for the request host and appends its params to ps
+func (table *routeTable) matchHost(requestHost string, ps *Params) *hostRouter {
+	requestHost = strings.ToLower(stripHostPort(requestHost))
+
+	for _, host := range table.hosts {
+		if host.match(requestHost, nil) {
+			host.match(requestHost, ps)
+			return host
+		}
+	}
+	return nil
+}
+
+// newHostRouter parses a host pattern
+func newHostRouter(pattern string) (*hostRouter, error) {
+	if pattern == "" {
+		return nil, errors.New("pattern must not be empty")
+	}
+
+	host := &hostRouter{
+		pattern: pattern,
+		labels:  strings.Split(pattern, "."),
+		kind:    hostExact,
+		trees:   make(methodTrees),
+	}
+
+	// An IPv6 address is matched as a whole, its colons are not params
+	if pattern[0] == '[' {
+		if len(pattern) < 3 || pattern[len(pattern)-1] != ']' {
+			return nil, errors.New("pattern has an invalid IPv6 address")
+		}
+		host.host = pattern[1 : len(pattern)-1]
+		host.literals = 1
+		return host, nil
+	}
+
+	for _, label := range host.labels {
+		switch {
+		case label == "":
+			return nil, errors.New("pattern has an empty label")
+		case label == "*":
+			host.kind = max(host.kind, hostWildcard)
+		case label[0] == ':':
+			if len(label) < 2 {
+				return nil, errors.New("host params must be named with a non-empty name")
+			}
+			host.kind = max(host.kind, hostParam)
+		default:
+			host.literals++
+		}
+	}
+	if host.kind == hostExact {
+		host.host = pattern
+	}
+	return host, nil
+}
+
+// match reports whether the request host matches the pattern.
+// If ps is not nil, the host params are appended to it.
+func (host *hostRouter) match(requestHost string, ps *Params) bool {
+	if host.kind == hostExact {
+		return requestHost == host.host
+	}
+
+	for i, label := range host.labels {
+		value := requestHost
+		if end := strings.IndexByte(requestHost, '.'); end >= 0 {
+			value, requestHost = requestHost[:end], requestHost[end+1:]
+		} else if i < len(host.labels)-1 {
+			return false
+		} else {
+			requestHost = ""
+		}
+
+		if label == "*" || label[0] == ':' {
+			if value == "" {
+				return false
+			}
+			if label[0] == ':' && ps != nil {
+				*ps = append(*ps, Param{Key: label[1:], Value: value})
+			}
+			continue
+		}
+		if label != value {
+			return false
+		}
+	}
+	return requestHost == ""
+}
+
+// stripHostPort removes the port from a Host header value
+func stripHostPort(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && strings.IndexByte(host[i:], ']') < 0 {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
@@ -0,0 +1,83 @@
+package drift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostMatching(t *testing.T) {
+	engine := newTestEngine()
+	respond := func(name string) HandlerFunc {
+		return func(c *Context) {
+			c.String(http.StatusOK, "%s %s", name, c.Param("tenant"))
+		}
+	}
+	engine.Get("/", respond("engine"))
+	engine.Host("*.example.com").Get("/", respond("wildcard"))
+	engine.Host(":tenant.example.com").Get("/", respond("tenant"))
+	engine.Host("api.example.com").Get("/", respond("api"))
+	engine.Host(":tenant.eu.example.com").Get("/", respond("eu"))
+	engine.Host("[::1]").Get("/", respond("ipv6"))
+
+	tests := []struct {
+		host string
+		body string
+	}{
+		{"api.example.com", "api "},         // exact before param and wildcard
+		{"acme.example.com", "tenant acme"}, // param before wildcard
+		{"acme.example.com:8080", "tenant acme"},
+		{"API.Example.COM", "api "},
+		{"ACME.example.com", "tenant acme"},
+		{"acme.eu.example.com", "eu acme"}, // more literal labels first
+		{"a.b.example.com", "engine "},     // labels match exactly one label
+		{"example.com", "engine "},
+		{".example.com", "engine "},
+		{"other.org", "engine "},
+		{"[::1]:8080", "ipv6 "},
+		{"[::1]", "ipv6 "},
+		{"", "engine "},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = tt.host
+		w := serve(engine, req)
+		if w.Code != http.StatusOK || w.Body.String() != tt.body {
+			t.Errorf("Host %q: got %d %q, want 200 %q", tt.host, w.Code, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestHostFallback(t *testing.T) {
+	engine := newTestEngine()
+	engine.Get("/status", func(c *Context) { c.String(http.StatusOK, "engine") })
+	engine.Host("admin.example.com").Get("/dashboard", func(c *Context) { c.String(http.StatusOK, "admin") })
+
+	tests := []struct {
+		host string
+		path string
+		code int
+	}{
+		{"admin.example.com", "/dashboard", http.StatusOK},
+		{"www.example.com", "/dashboard", http.StatusNotFound},
+		{"www.example.com", "/status", http.StatusOK},
+		// A matched host only uses its own routes
+		{"admin.example.com", "/status", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+		if w := serve(engine, req); w.Code != tt.code {
+			t.Errorf("%s%s: got %d, want %d", tt.host, tt.path, w.Code, tt.code)
+		}
+	}
+}
+
+func TestHostInvalidIPv6(t *testing.T) {
+	engine := newTestEngine()
+	engine.CollectRouteErrors = true
+	engine.Host("[::1")
+	if err := engine.Validate(); err == nil || err.Error() != "1 invalid route(s):\n  host '[::1': pattern has an invalid IPv6 address" {
+		t.Errorf("Validate() = %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package drift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newMethodsEngine returns an engine with routes for the HEAD, OPTIONS
+// and 405 tests
+func newMethodsEngine(handleHEAD, handleOPTIONS bool) *Engine {
+	engine := newTestEngine()
+	engine.HandleHEAD = handleHEAD
+	engine.HandleOPTIONS = handleOPTIONS
+
+	handler := func(name string) HandlerFunc {
+		return func(c *Context) {
+			c.Header("X-Handler", name)
+			c.String(http.StatusOK, "%s", name)
+		}
+	}
+	engine.Get("/users", handler("list"))
+	engine.Post("/users", handler("create"))
+	engine.Delete("/users/:id", handler("delete"))
+	engine.Get("/explicit", handler("get"))
+	engine.Head("/explicit", handler("head"))
+	engine.Options("/explicit", handler("options"))
+	return engine
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		name          string
+		handleHEAD    bool
+		handleOPTIONS bool
+		method        string
+		path          string
+		code          int
+		body          string
+		header        map[string]string
+	}{
+		{"HEAD from GET", true, true, http.MethodHead, "/users", http.StatusOK, "",
+			map[string]string{"Content-Length": "4", "X-Handler": "list"}},
+		{"explicit HEAD", true, true, http.MethodHead, "/explicit", http.StatusOK, "head",
+			map[string]string{"X-Handler": "head"}},
+		{"automatic OPTIONS", true, true, http.MethodOptions, "/users", http.StatusNoContent, "",
+			map[string]string{"Allow": "GET, HEAD, OPTIONS, POST"}},
+		{"explicit OPTIONS", true, true, http.MethodOptions, "/explicit", http.StatusOK, "options",
+			map[string]string{"Allow": "", "X-Handler": "options"}},
+		{"405", true, true, http.MethodPut, "/users", http.StatusMethodNotAllowed, `{"error":"Method Not Allowed"}` + "\n",
+			map[string]string{"Allow": "GET, HEAD, OPTIONS, POST"}},
+		{"405 for params", true, true, http.MethodGet, "/users/42", http.StatusMethodNotAllowed, "",
+			map[string]string{"Allow": "DELETE, OPTIONS"}},
+		{"405 lists explicit HEAD and OPTIONS", true, true, http.MethodPost, "/explicit", http.StatusMethodNotAllowed, "",
+			map[string]string{"Allow": "GET, HEAD, OPTIONS"}},
+		{"404", true, true, http.MethodGet, "/missing", http.StatusNotFound, `{"error":"Not Found"}` + "\n", nil},
+		{"HandleHEAD off", false, true, http.MethodHead, "/users", http.StatusMethodNotAllowed, `{"error":"Method Not Allowed"}` + "\n",
+			map[string]string{"Allow": "GET, OPTIONS, POST"}},
+		{"HandleOPTIONS off", true, false, http.MethodOptions, "/users", http.StatusMethodNotAllowed, "",
+			map[string]string{"Allow": "GET, HEAD, POST"}},
+		{"both off", false, false, http.MethodPut, "/users", http.StatusMethodNotAllowed, "",
+			map[string]string{"Allow": "GET, POST"}},
+	}
+	for _, tt := range tests {
+		engine := newMethodsEngine(tt.handleHEAD, tt.handleOPTIONS)
+		w := serve(engine, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || (tt.body != "" || tt.method == http.MethodHead) && w.Body.String() != tt.body {
+			t.Errorf("%s: %s %s got %d %q, want %d %q", tt.name, tt.method, tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		for key, value := range tt.header {
+			if got := w.Header().Get(key); got != value {
+				t.Errorf("%s: %s = %q, want %q", tt.name, key, got, value)
+			}
+		}
+	}
+}
+
+func TestNoRouteNoMethod(t *testing.T) {
+	engine := newMethodsEngine(true, true)
+	var order []string
+	engine.Use(func(c *Context) {
+		order = append(order, "middleware")
+		c.Next()
+	})
+	engine.NoRoute(func(c *Context) {
+		order = append(order, "no route")
+		c.String(http.StatusTeapot, "custom 404")
+	})
+	engine.NoMethod(func(c *Context) {
+		order = append(order, "no method "+c.Response.Header().Get("Allow"))
+		c.String(http.StatusTeapot, "custom 405")
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		order  string
+	}{
+		{http.MethodGet, "/missing", "custom 404", "middleware, no route"},
+		{http.MethodPut, "/users", "custom 405", "middleware, no method GET, HEAD, OPTIONS, POST"},
+	}
+	for _, tt := range tests {
+		order = nil
+		w := serve(engine, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != http.StatusTeapot || w.Body.String() != tt.body {
+			t.Errorf("%s %s: got %d %q, want 418 %q", tt.method, tt.path, w.Code, w.Body.String(), tt.body)
+		}
+		if got := strings.Join(order, ", "); got != tt.order {
+			t.Errorf("%s %s: ran %q, want %q", tt.method, tt.path, got, tt.order)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package drift
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountMethods are the methods forwarded to a mounted handler
+var mountMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// WrapH wraps an http.Handler so it can be used as a drift handler
+func WrapH(h http.Handler) HandlerFunc {
+	return nameWrapper(func(c *Context) {
+		h.ServeHTTP(c.Response, c.Request)
+	}, nameOfHTTPHandler(h))
+}
+
+// WrapF wraps an http.HandlerFunc so it can be used as a drift handler
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return nameWrapper(func(c *Context) {
+		f(c.Response, c.Request)
+	}, nameOfFunction(f))
+}
+
+// Mount forwards all requests under relativePath to handler, with the prefix
+// stripped from the request path. The group's middleware runs first.
+// Another *Engine can be mounted as a sub-application; it keeps its own
+// middleware and 404 handling, and its redirects keep the mount prefix.
+//
+//	app.Mount("/debug/pprof", http.DefaultServeMux)
+//	app.Mount("/legacy", legacyApp)
+func (group *RouterGroup) Mount(relativePath string, handler http.Handler) {
+	prefix := strings.TrimSuffix(group.calculateAbsolutePath(relativePath), "/")
+
+	mounted := nameWrapper(func(c *Context) {
+		handler.ServeHTTP(c.Response, stripPrefix(c.Request, prefix))
+	}, nameOfHTTPHandler(handler))
+
+	for _, method := range mountMethods {
+		if prefix != "" {
+			group.handleAbsolute(method, prefix, []HandlerFunc{mounted})
+		}
+		group.handleAbsolute(method, prefix+"/*path", []HandlerFunc{mounted})
+	}
+}
+
+// mountPrefixKey is the request context key of the prefixes stripped by Mount
+type mountPrefixKey struct{}
+
+// mountPrefix returns the prefix stripped from req's path by Mount, if any
+func mountPrefix(req *http.Request) string {
+	prefix, _ := req.Context().Value(mountPrefixKey{}).(string)
+	return prefix
+}
+
+// stripPrefix returns a shallow copy of req with prefix removed from its path.
+// The stripped prefix is recorded in the request context for redirects.
+func stripPrefix(req *http.Request, prefix string) *http.Request {
+	if prefix == "" {
+		return req
+	}
+
+	r := req.WithContext(context.WithValue(req.Context(), mountPrefixKey{}, mountPrefix(req)+prefix))
+	r.URL = new(url.URL)
+	*r.URL = *req.URL
+	r.URL.Path = strings.TrimPrefix(req.URL.Path, prefix)
+	r.URL.RawPath = strings.TrimPrefix(req.URL.RawPath, prefix)
+	if r.URL.Path == "" {
+		r.URL.Path = "/"
+	}
+	if req.URL.RawPath != "" && r.URL.RawPath == "" {
+		r.URL.RawPath = "/"
+	}
+	return r
+}
@@ -0,0 +1,76 @@
+package drift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMountRedirects(t *testing.T) {
+	sub := newTestEngine()
+	sub.RedirectFixedPath = true
+	sub.Get("/users", func(c *Context) { c.String(http.StatusOK, "users") })
+
+	mid := newTestEngine()
+	mid.Mount("/v1", sub)
+
+	app := newTestEngine()
+	app.Mount("/legacy", sub)
+	app.Group("/api").Mount("/old", mid)
+	app.Mount("/slash/", sub)
+	app.Group("/v2").Mount("/slash/", sub)
+
+	tests := []struct {
+		path     string
+		code     int
+		location string
+	}{
+		{"/legacy/users", http.StatusOK, ""},
+		{"/legacy/users/", http.StatusMovedPermanently, "/legacy/users"},
+		{"/legacy/USERS?page=2", http.StatusMovedPermanently, "/legacy/users?page=2"},
+		{"/api/old/v1/users", http.StatusOK, ""},
+		{"/api/old/v1/users/", http.StatusMovedPermanently, "/api/old/v1/users"},
+		{"/slash/users", http.StatusOK, ""},
+		{"/slash/users/", http.StatusMovedPermanently, "/slash/users"},
+		{"/v2/slash/users", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := serve(app, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if location := w.Header().Get("Location"); w.Code != tt.code || location != tt.location {
+			t.Errorf("GET %s: got %d to %q, want %d to %q", tt.path, w.Code, location, tt.code, tt.location)
+		}
+	}
+}
+
+func TestMountKeepsRequest(t *testing.T) {
+	var inner, outer string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner = r.URL.Path
+	})
+
+	app := newTestEngine()
+	app.Use(func(c *Context) {
+		c.Next()
+		outer = c.Request.URL.Path
+	})
+	app.Mount("/legacy", handler)
+	app.Mount("/slash/", handler)
+
+	tests := []struct {
+		path  string
+		inner string
+	}{
+		{"/legacy/orders", "/orders"},
+		{"/legacy", "/"},
+		{"/slash/orders", "/orders"},
+		{"/slash", "/"},
+		{"/slash/", "/"},
+	}
+	for _, tt := range tests {
+		inner, outer = "", ""
+		w := serve(app, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != http.StatusOK || inner != tt.inner || outer != tt.path {
+			t.Errorf("GET %s: got %d, handler saw %q and middleware %q, want 200, %q and %q", tt.path, w.Code, inner, outer, tt.inner, tt.path)
+		}
+	}
+}
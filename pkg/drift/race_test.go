@@ -0,0 +1,7 @@
+//go:build race
+
+package drift
+
+// raceEnabled reports whether tests run with the race detector, which makes
+// sync.Pool drop contexts at random
+const raceEnabled = true
@@ -0,0 +1,108 @@
+package drift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRawPath(t *testing.T) {
+	engine := newTestEngine()
+	engine.UseRawPath = true
+	engine.Get("/files/:name", func(c *Context) { c.String(http.StatusOK, "%s", c.Param("name")) })
+	engine.Get("/dirs/:name/", func(c *Context) { c.String(http.StatusOK, "%s", c.Param("name")) })
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/files/a%2Fb", http.StatusOK, "a/b"},           // encoded slash stays in the param
+		{"/files/a%252Fb", http.StatusOK, "a%2Fb"},       // double-encoding is decoded exactly once
+		{"/files/caf%C3%A9", http.StatusOK, "café"},      // other escapes are decoded
+		{"/files/a%20b%2F%2Fc", http.StatusOK, "a b//c"}, // mixed escapes
+		{"/files/a/b", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(engine, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code || (tt.code == http.StatusOK && w.Body.String() != tt.body) {
+			t.Errorf("GET %s: got %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+
+	// Without UseRawPath the decoded path is routed
+	engine.UseRawPath = false
+	if w := serve(engine, httptest.NewRequest(http.MethodGet, "/files/a%2Fb", nil)); w.Code != http.StatusNotFound {
+		t.Errorf("GET /files/a%%2Fb without UseRawPath: got %d, want 404", w.Code)
+	}
+}
+
+func TestRawPathInvalidEscape(t *testing.T) {
+	engine := newTestEngine()
+	engine.UseRawPath = true
+	engine.Get("/files/:name", func(c *Context) { c.String(http.StatusOK, "%s", c.Param("name")) })
+	engine.Get("/dirs/:name/", func(c *Context) { c.String(http.StatusOK, "%s", c.Param("name")) })
+
+	// An invalid raw path falls back to URL.Path
+	req := httptest.NewRequest(http.MethodGet, "/files/x", nil)
+	req.URL.RawPath = "/files/%zz"
+	if w := serve(engine, req); w.Code != http.StatusOK || w.Body.String() != "x" {
+		t.Errorf("got %d %q, want 200 \"x\"", w.Code, w.Body.String())
+	}
+
+	// and redirects are built from URL.Path too
+	req = httptest.NewRequest(http.MethodGet, "/dirs/a%2541", nil)
+	req.URL.RawPath = "/dirs/a%zz"
+	w := serve(engine, req)
+	if location := w.Header().Get("Location"); w.Code != http.StatusMovedPermanently || location != "/dirs/a%2541/" {
+		t.Errorf("got %d to %q, want 301 to \"/dirs/a%%2541/\"", w.Code, location)
+	}
+}
+
+func TestRawPathRedirect(t *testing.T) {
+	engine := newTestEngine()
+	engine.UseRawPath = true
+	engine.RedirectFixedPath = true
+	engine.Get("/dirs/:name/", func(c *Context) { c.String(http.StatusOK, "%s", c.Param("name")) })
+	engine.Get("/Docs/:name", func(c *Context) { c.String(http.StatusOK, "%s", c.Param("name")) })
+
+	tests := []struct {
+		path     string
+		location string
+	}{
+		{"/dirs/a%2Fb?x=1", "/dirs/a%2Fb/?x=1"},
+		{"/dirs/a%252Fb", "/dirs/a%252Fb/"},
+		{"/docs/a%2Fb", "/Docs/a%2Fb"},
+	}
+	for _, tt := range tests {
+		w := serve(engine, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if location := w.Header().Get("Location"); w.Code != http.StatusMovedPermanently || location != tt.location {
+			t.Errorf("GET %s: got %d to %q, want 301 to %q", tt.path, w.Code, location, tt.location)
+		}
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	engine := newTestEngine()
+	engine.CaseInsensitive = true
+	engine.Get("/about", func(c *Context) { c.String(http.StatusOK, "lower") })
+	engine.Get("/About", func(c *Context) { c.String(http.StatusOK, "title") })
+	engine.Get("/café/:id", func(c *Context) { c.String(http.StatusOK, "café %s", c.Param("id")) })
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/about", "lower"}, // exact matches win over folded ones
+		{"/About", "title"},
+		{"/caf%C3%A9/1", "café 1"},
+		{"/CAF%C3%89/2", "café 2"}, // non-ASCII letters fold too
+		{"/Caf%C3%A9/Ab", "café Ab"},
+	}
+	for _, tt := range tests {
+		w := serve(engine, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != tt.body {
+			t.Errorf("GET %s: got %d %q, want 200 %q", tt.path, w.Code, w.Body.String(), tt.body)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package drift
 
+import "log"
+
 // RouterGroup is used internally to configure router groups
 type RouterGroup struct {
 	handlers []HandlerFunc
 	basePath string
 	engine   *Engine
+	host     string // host pattern, empty for routes matching all hosts
 }
 
 // Group creates a new router group with the given path prefix
@@ -13,6 +16,7 @@ func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *R
 		handlers: group.combineHandlers(handlers),
 		basePath: group.calculateAbsolutePath(relativePath),
 		engine:   group.engine,
+		host:     group.host,
 	}
 }
 
@@ -22,38 +26,38 @@ func (group *RouterGroup) Use(middleware ...HandlerFunc) {
 }
 
 // Get registers a GET route
-func (group *RouterGroup) Get(relativePath string, handlers ...HandlerFunc) {
-	group.handle("GET", relativePath, handlers)
+func (group *RouterGroup) Get(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("GET", relativePath, handlers)
 }
 
 // Post registers a POST route
-func (group *RouterGroup) Post(relativePath string, handlers ...HandlerFunc) {
-	group.handle("POST", relativePath, handlers)
+func (group *RouterGroup) Post(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("POST", relativePath, handlers)
 }
 
 // Put registers a PUT route
-func (group *RouterGroup) Put(relativePath string, handlers ...HandlerFunc) {
-	group.handle("PUT", relativePath, handlers)
+func (group *RouterGroup) Put(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("PUT", relativePath, handlers)
 }
 
 // Delete registers a DELETE route
-func (group *RouterGroup) Delete(relativePath string, handlers ...HandlerFunc) {
-	group.handle("DELETE", relativePath, handlers)
+func (group *RouterGroup) Delete(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("DELETE", relativePath, handlers)
 }
 
 // Patch registers a PATCH route
-func (group *RouterGroup) Patch(relativePath string, handlers ...HandlerFunc) {
-	group.handle("PATCH", relativePath, handlers)
+func (group *RouterGroup) Patch(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("PATCH", relativePath, handlers)
 }
 
 // Options registers an OPTIONS route
-func (group *RouterGroup) Options(relativePath string, handlers ...HandlerFunc) {
-	group.handle("OPTIONS", relativePath, handlers)
+func (group *RouterGroup) Options(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("OPTIONS", relativePath, handlers)
 }
 
 // Head registers a HEAD route
-func (group *RouterGroup) Head(relativePath string, handlers ...HandlerFunc) {
-	group.handle("HEAD", relativePath, handlers)
+func (group *RouterGroup) Head(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("HEAD", relativePath, handlers)
 }
 
 // Any registers a route that matches all HTTP methods
@@ -64,22 +68,65 @@ func (group *RouterGroup) Any(relativePath string, handlers ...HandlerFunc) {
 	}
 }
 
-// Static serves files from the given file system root
-func (group *RouterGroup) Static(relativePath, root string) {
-	handler := func(c *Context) {
-		// Simple static file serving
-		// In production, you'd want to use http.FileServer
-		c.String(200, "Static file serving for: "+root)
+// AddRoute registers a route for any HTTP method. Like the other registration
+// methods it is safe to call while the server is running; requests in flight
+// keep using the routes they started with.
+func (group *RouterGroup) AddRoute(httpMethod, relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle(httpMethod, relativePath, handlers)
+}
+
+// RemoveRoute removes the route registered for the method and path, as given
+// when it was added, and reports whether it existed. It is safe to call while
+// the server is running.
+func (group *RouterGroup) RemoveRoute(httpMethod, relativePath string) bool {
+	absolutePath := group.calculateAbsolutePath(relativePath)
+
+	var removed *Route
+	group.engine.updateRoutes(func(table *routeTable) {
+		trees := table.methodTrees(group.host)
+		root := trees[httpMethod]
+		if root == nil {
+			return
+		}
+		root, removed = root.RemoveRoute(absolutePath)
+		trees[httpMethod] = root
+		if removed != nil {
+			removed.registered = false
+		}
+	})
+	if removed == nil {
+		return false
+	}
+
+	group.engine.routesMu.Lock()
+	if removed.name != "" && group.engine.namedRoutes[removed.name] == removed {
+		delete(group.engine.namedRoutes, removed.name)
 	}
-	urlPattern := joinPaths(relativePath, "/*filepath")
-	group.Get(urlPattern, handler)
+	group.engine.routesMu.Unlock()
+
+	if group.engine.IsDebug() {
+		log.Printf("[DRIFT] removed %-7s %s%s", httpMethod, group.host, absolutePath)
+	}
+	return true
 }
 
 // handle registers a new request handle and middleware with the given path and method
-func (group *RouterGroup) handle(httpMethod, relativePath string, handlers []HandlerFunc) {
-	absolutePath := group.calculateAbsolutePath(relativePath)
-	handlers = group.combineHandlers(handlers)
-	group.engine.addRoute(httpMethod, absolutePath, handlers)
+func (group *RouterGroup) handle(httpMethod, relativePath string, handlers []HandlerFunc) *Route {
+	return group.handleAbsolute(httpMethod, group.calculateAbsolutePath(relativePath), handlers)
+}
+
+// handleAbsolute registers a route with the group's host and middleware
+// at an absolute path
+func (group *RouterGroup) handleAbsolute(httpMethod, absolutePath string, handlers []HandlerFunc) *Route {
+	route := &Route{
+		Method:   httpMethod,
+		Path:     absolutePath,
+		Host:     group.host,
+		handlers: group.combineHandlers(handlers),
+		engine:   group.engine,
+	}
+	group.engine.addRoute(route)
+	return route
 }
 
 // calculateAbsolutePath calculates the absolute path for a relative path
@@ -0,0 +1,379 @@
+package drift
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/m1z23r/drift/internal/router"
+)
+
+// Route is a registered route. It is returned by the route registration
+// methods so the route can be configured further.
+type Route struct {
+	Method   string
+	Path     string
+	Host     string // host pattern, empty for all hosts
+	name     string
+	handlers []HandlerFunc
+	engine   *Engine
+	pattern  *router.Pattern // parsed Path, set when the route is added
+
+	// Replaced as a whole by SetMeta, so requests can read it while
+	// metadata is added to a route registered at runtime
+	meta atomic.Pointer[map[string]any]
+
+	// Whether the route is in the route table, guarded by engine.routesMu
+	registered bool
+}
+
+// Name names the route so its URL can be built with Engine.URL.
+// It panics if the name is already taken by another route. Routes that
+// were rejected or removed are not named.
+func (r *Route) Name(name string) *Route {
+	r.engine.routesMu.Lock()
+	defer r.engine.routesMu.Unlock()
+
+	if !r.registered {
+		return r
+	}
+
+	if existing, ok := r.engine.namedRoutes[name]; ok && existing != r {
+		err := &RouteError{
+			Method:   r.Method,
+			Host:     r.Host,
+			Path:     r.Path,
+			Existing: existing.Method + " " + existing.Path,
+			Reason:   "route name '" + name + "' is already registered",
+		}
+		if !r.engine.CollectRouteErrors {
+			panic(err.Error())
+		}
+		r.engine.routeErrors = append(r.engine.routeErrors, err)
+		return r
+	}
+	if r.name != "" {
+		delete(r.engine.namedRoutes, r.name)
+	}
+	r.name = name
+	r.engine.namedRoutes[name] = r
+	return r
+}
+
+// SetMeta attaches metadata to the route, e.g. required scopes or a
+// rate-limit class, that middleware can read through Context.Route.
+// It is safe to call while the route is serving requests.
+//
+//	app.Get("/admin/users", listUsers).SetMeta("scopes", []string{"users:read"})
+func (r *Route) SetMeta(key string, value any) *Route {
+	r.engine.routesMu.Lock()
+	defer r.engine.routesMu.Unlock()
+
+	meta := make(map[string]any)
+	if current := r.meta.Load(); current != nil {
+		meta = maps.Clone(*current)
+	}
+	meta[key] = value
+	r.meta.Store(&meta)
+	return r
+}
+
+// metadata returns the route's metadata map, nil if there is none
+func (r *Route) metadata() map[string]any {
+	if meta := r.meta.Load(); meta != nil {
+		return *meta
+	}
+	return nil
+}
+
+// Meta returns the metadata stored under key, or nil if not set.
+// It is safe to call on a nil route.
+func (r *Route) Meta(key string) any {
+	if r == nil {
+		return nil
+	}
+	return r.metadata()[key]
+}
+
+// MetaString returns the metadata stored under key as a string
+func (r *Route) MetaString(key string) string {
+	if s, ok := r.Meta(key).(string); ok {
+		return s
+	}
+	return ""
+}
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method       string
+	Path         string
+	Host         string // host pattern, empty for all hosts
+	Name         string
+	Handler      string // name of the last handler in the chain, or of the function it wraps
+	HandlerCount int    // number of handlers including middleware
+	Meta         map[string]any
+}
+
+// Routes returns all registered routes sorted by host, path and method
+func (engine *Engine) Routes() []RouteInfo {
+	var routes []RouteInfo
+	collect := func(trees methodTrees) {
+		for _, root := range trees {
+			root.Walk(func(_ string, route *Route) {
+				info := RouteInfo{
+					Method:       route.Method,
+					Path:         route.Path,
+					Host:         route.Host,
+					Name:         route.name,
+					HandlerCount: len(route.handlers),
+					Meta:         maps.Clone(route.metadata()),
+				}
+				if len(route.handlers) > 0 {
+					info.Handler = nameOfHandler(route.handlers[len(route.handlers)-1])
+				}
+				routes = append(routes, info)
+			})
+		}
+	}
+	engine.routesMu.RLock()
+	table := engine.routes.Load()
+	collect(table.trees)
+	for _, host := range table.hosts {
+		collect(host.trees)
+	}
+	engine.routesMu.RUnlock()
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// URL builds the URL of the named route. Params are given as key/value pairs;
+// values fill the :param and *catchAll segments of the route and any pairs
+// left over are added as the query string.
+//
+//	app.Get("/users/:id", showUser).Name("user.show")
+//	url, err := app.URL("user.show", "id", 42, "tab", "posts") // /users/42?tab=posts
+func (engine *Engine) URL(name string, params ...any) (string, error) {
+	engine.routesMu.RLock()
+	route, ok := engine.namedRoutes[name]
+	engine.routesMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("route %q is not registered", name)
+	}
+
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("route %q: params must be key/value pairs", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	keys := make([]string, 0, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("route %q: param key %v is not a string", name, params[i])
+		}
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	used := make(map[string]bool, len(keys))
+	path, err := route.pattern.Build(func(key string) (string, bool) {
+		used[key] = true
+		value, ok := values[key]
+		return value, ok
+	})
+	if err != nil {
+		return "", fmt.Errorf("route %q: %w", name, err)
+	}
+
+	query := url.Values{}
+	for _, key := range keys {
+		if !used[key] {
+			query.Set(key, values[key])
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
+
+// RouteError reports a route that could not be registered
+type RouteError struct {
+	Method   string // empty if the host pattern is invalid
+	Host     string // host pattern, empty for all hosts
+	Path     string // pattern of the rejected route
+	Existing string // conflicting registered route, empty if the pattern is invalid
+	Reason   string
+}
+
+// Error implements the error interface
+func (e *RouteError) Error() string {
+	if e.Method == "" {
+		return "host '" + e.Host + "': " + e.Reason
+	}
+	route := e.Method + " " + e.Host + e.Path
+	if e.Existing == "" {
+		return route + ": " + e.Reason
+	}
+	return route + " conflicts with " + e.Existing + ": " + e.Reason
+}
+
+// RouteErrors lists every route that could not be registered
+type RouteErrors []*RouteError
+
+// Error implements the error interface, one route per line
+func (errs RouteErrors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d invalid route(s):", len(errs))
+	for _, err := range errs {
+		b.WriteString("\n  ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Validate returns the routes rejected since the engine was created as
+// RouteErrors, or nil if all routes were registered. Errors are only
+// collected with CollectRouteErrors; otherwise registration panics.
+func (engine *Engine) Validate() error {
+	engine.routesMu.RLock()
+	defer engine.routesMu.RUnlock()
+
+	if len(engine.routeErrors) == 0 {
+		return nil
+	}
+	return slices.Clone(engine.routeErrors)
+}
+
+// newRouteError describes why route could not be added to its tree
+func newRouteError(route *Route, err error) *RouteError {
+	routeErr := &RouteError{
+		Method: route.Method,
+		Host:   route.Host,
+		Path:   route.Path,
+		Reason: err.Error(),
+	}
+
+	var conflict *router.ConflictError
+	if errors.As(err, &conflict) {
+		routeErr.Existing = route.Method + " " + route.Host + conflict.Existing
+		routeErr.Reason = conflict.Reason
+	}
+	return routeErr
+}
+
+// routeError records err with CollectRouteErrors, or panics
+func (engine *Engine) routeError(err *RouteError) {
+	if !engine.CollectRouteErrors {
+		panic(err.Error())
+	}
+
+	engine.routesMu.Lock()
+	engine.routeErrors = append(engine.routeErrors, err)
+	engine.routesMu.Unlock()
+}
+
+// routeTable is a snapshot of the registered routes. A table is never
+// modified once it is in use; updateRoutes builds and stores a new one.
+type routeTable struct {
+	trees methodTrees   // routes without a host
+	hosts []*hostRouter // host-specific routes, in match order
+}
+
+// methodTrees returns the trees of the routes for host, or of the routes
+// without a host if host is empty. It returns nil if the host was rejected.
+func (table *routeTable) methodTrees(host string) methodTrees {
+	if host == "" {
+		return table.trees
+	}
+	if h := table.findHost(host); h != nil {
+		return h.trees
+	}
+	return nil
+}
+
+// updateRoutes applies change to a copy of the current route table and
+// swaps it in. The trees themselves are copied on write by the router.
+func (engine *Engine) updateRoutes(change func(table *routeTable)) {
+	engine.routesMu.Lock()
+	defer engine.routesMu.Unlock()
+
+	current := engine.routes.Load()
+	table := &routeTable{
+		trees: maps.Clone(current.trees),
+		hosts: make([]*hostRouter, len(current.hosts)),
+	}
+	for i, host := range current.hosts {
+		clone := *host
+		clone.trees = maps.Clone(host.trees)
+		table.hosts[i] = &clone
+	}
+
+	change(table)
+	engine.routes.Store(table)
+}
+
+// nameOfFunction returns the runtime name of a function
+func nameOfFunction(f any) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// wrapperNames maps the closures returned by WrapE, WrapH, WrapF and Mount
+// to the name of what they wrap, so Routes doesn't report "WrapE.func1".
+// Entries are removed when the closure is garbage collected.
+var wrapperNames sync.Map // closure address -> *string
+
+// closureAddr returns the address of the closure object behind h
+func closureAddr(h HandlerFunc) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&h))
+}
+
+// nameWrapper records name as the name of the handler wrapped by h
+func nameWrapper(h HandlerFunc, name string) HandlerFunc {
+	p := closureAddr(h)
+	key, value := uintptr(p), &name
+	wrapperNames.Store(key, value)
+	// Compare the value so a cleanup doesn't remove the entry of a new
+	// closure allocated at the same address
+	runtime.AddCleanup((*byte)(p), func(key uintptr) {
+		wrapperNames.CompareAndDelete(key, value)
+	}, key)
+	return h
+}
+
+// nameOfHandler returns the name of h, or of the function it wraps
+func nameOfHandler(h HandlerFunc) string {
+	if name, ok := wrapperNames.Load(uintptr(closureAddr(h))); ok {
+		return *name.(*string)
+	}
+	return nameOfFunction(h)
+}
+
+// nameOfHTTPHandler returns the function name of an http.HandlerFunc,
+// or the type of any other http.Handler
+func nameOfHTTPHandler(h http.Handler) string {
+	if f, ok := h.(http.HandlerFunc); ok {
+		return nameOfFunction(f)
+	}
+	return fmt.Sprintf("%T", h)
+}
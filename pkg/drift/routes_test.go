@@ -0,0 +1,166 @@
+package drift
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRuntimeRoutes(t *testing.T) {
+	engine := newTestEngine()
+	engine.Get("/static", func(c *Context) { c.String(http.StatusOK, "static") })
+
+	// Requests run concurrently with route changes and SetMeta
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if w := serve(engine, httptest.NewRequest(http.MethodGet, "/static", nil)); w.Code != http.StatusOK {
+					t.Errorf("GET /static = %d during route changes", w.Code)
+					return
+				}
+				serve(engine, httptest.NewRequest(http.MethodGet, "/dyn/3/x", nil))
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		path := fmt.Sprintf("/dyn/%d/:id", i)
+		engine.AddRoute(http.MethodGet, path, func(c *Context) {
+			c.String(http.StatusOK, "%v", c.Route().Meta("k"))
+		}).SetMeta("k", i)
+		if i%2 == 1 && !engine.RemoveRoute(http.MethodGet, path) {
+			t.Errorf("RemoveRoute(%q) = false", path)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if w := serve(engine, httptest.NewRequest(http.MethodGet, "/dyn/4/x", nil)); w.Body.String() != "4" {
+		t.Errorf("GET /dyn/4/x = %d %q, want 200 \"4\"", w.Code, w.Body.String())
+	}
+	if w := serve(engine, httptest.NewRequest(http.MethodGet, "/dyn/5/x", nil)); w.Code != http.StatusNotFound {
+		t.Errorf("GET of a removed route = %d, want 404", w.Code)
+	}
+	if engine.RemoveRoute(http.MethodGet, "/dyn/5/:id") {
+		t.Error("RemoveRoute of a removed route = true")
+	}
+}
+
+func TestRouteNames(t *testing.T) {
+	engine := newTestEngine()
+	engine.CollectRouteErrors = true
+	h := func(c *Context) {}
+
+	engine.Get("/users/:id", h).Name("user")
+	engine.Get("/users/:name", h).Name("rejected")
+	if _, err := engine.URL("rejected"); err == nil {
+		t.Error("a rejected route was named")
+	}
+
+	if !engine.RemoveRoute(http.MethodGet, "/users/:id") {
+		t.Fatal("RemoveRoute = false")
+	}
+	if _, err := engine.URL("user", "id", 1); err == nil {
+		t.Error("the name of a removed route is still registered")
+	}
+
+	// The name is free again
+	engine.Get("/people/:id", h).Name("user")
+	if url, err := engine.URL("user", "id", 1); err != nil || url != "/people/1" {
+		t.Errorf("URL(user) = %q, %v, want /people/1", url, err)
+	}
+}
+
+func listUsers(c *Context) {}
+
+func getUser(c *Context) error { return nil }
+
+func serveLegacy(w http.ResponseWriter, r *http.Request) {}
+
+func TestRouteHandlerNames(t *testing.T) {
+	engine := newTestEngine()
+	engine.Get("/users", listUsers)
+	engine.Get("/users/:id", WrapE(getUser))
+	engine.Get("/legacy", WrapF(serveLegacy))
+	engine.Get("/metrics", WrapH(http.HandlerFunc(serveLegacy)))
+	engine.Get("/mux", WrapH(http.NewServeMux()))
+	engine.Mount("/sub", newTestEngine())
+
+	const pkg = "github.com/m1z23r/drift/pkg/drift."
+	want := map[string]string{
+		"/users":     pkg + "listUsers",
+		"/users/:id": pkg + "getUser",
+		"/legacy":    pkg + "serveLegacy",
+		"/metrics":   pkg + "serveLegacy",
+		"/mux":       "*http.ServeMux",
+		"/sub":       "*drift.Engine",
+		"/sub/*path": "*drift.Engine",
+	}
+	for _, route := range engine.Routes() {
+		if route.Method != http.MethodGet {
+			continue
+		}
+		if route.Handler != want[route.Path] {
+			t.Errorf("%s: Handler = %q, want %q", route.Path, route.Handler, want[route.Path])
+		}
+	}
+}
+
+func TestCollectHostErrors(t *testing.T) {
+	engine := newTestEngine()
+	engine.CollectRouteErrors = true
+	handler := func(c *Context) {}
+
+	engine.Host("api..example.com").Get("/users", handler)
+	engine.Host(":.example.com")
+	engine.Host("")
+	engine.Host("admin.example.com").Get("/", handler)
+
+	err := engine.Validate()
+	var errs RouteErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Validate() = %v, want RouteErrors", err)
+	}
+	want := []string{
+		"host 'api..example.com': pattern has an empty label",
+		"GET api..example.com/users: host 'api..example.com' is not registered",
+		"host ':.example.com': host params must be named with a non-empty name",
+		"host '': pattern must not be empty",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, e := range errs {
+		if e.Error() != want[i] {
+			t.Errorf("error %d = %q, want %q", i, e.Error(), want[i])
+		}
+	}
+
+	// Valid hosts are still registered
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "admin.example.com"
+	if w := serve(engine, req); w.Code != http.StatusOK {
+		t.Errorf("GET admin.example.com/ = %d, want 200", w.Code)
+	}
+}
+
+func TestHostErrorsPanic(t *testing.T) {
+	defer func() {
+		if recovered := recover(); recovered != "host 'a..b': pattern has an empty label" {
+			t.Errorf("recovered %v", recovered)
+		}
+	}()
+	newTestEngine().Host("a..b")
+}
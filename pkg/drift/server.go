@@ -0,0 +1,340 @@
+package drift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long RunContext waits for in-flight requests
+// to finish once its context is cancelled
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ServerConfig defines the http.Server settings applied by all Run methods.
+// In release mode, zero values are replaced by DefaultServerConfig.
+type ServerConfig struct {
+	// ReadHeaderTimeout is the time allowed to read the request headers.
+	// It protects against slowloris attacks.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout is the time allowed to read the entire request, including the body
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the time allowed to write the response.
+	// It is not set by default since it would cut off long-lived SSE streams.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is how long keep-alive connections wait for the next request
+	IdleTimeout time.Duration
+
+	// MaxHeaderBytes limits the size of the request headers
+	MaxHeaderBytes int
+
+	// ErrorLog receives errors from accepting connections and from handlers.
+	// If nil, the log package's standard logger is used.
+	ErrorLog *log.Logger
+}
+
+// DefaultServerConfig returns the server settings used in release mode
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       60 * time.Second,
+		WriteTimeout:      0,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    1 << 20, // 1 MB
+	}
+}
+
+// SetServerConfig sets the http.Server settings used by the Run methods
+func (engine *Engine) SetServerConfig(config ServerConfig) {
+	engine.serverConfig = config
+}
+
+// SetShutdownTimeout sets how long RunContext waits for in-flight requests
+// to finish during a graceful shutdown
+func (engine *Engine) SetShutdownTimeout(timeout time.Duration) {
+	engine.shutdownTimeout = timeout
+}
+
+// Run starts the HTTP server
+func (engine *Engine) Run(addr string) error {
+	return engine.RunContext(context.Background(), addr)
+}
+
+// RunContext starts the HTTP server and shuts it down gracefully once ctx is done.
+// In-flight requests get up to the shutdown timeout to finish.
+func (engine *Engine) RunContext(ctx context.Context, addr string) error {
+	engine.logStartup(addr)
+	srv := engine.newServer(addr)
+	return engine.serve(ctx, srv, srv.ListenAndServe)
+}
+
+// RunTLS starts the HTTPS server
+func (engine *Engine) RunTLS(addr, certFile, keyFile string) error {
+	engine.logStartup(addr)
+	srv := engine.newServer(addr)
+	return engine.serve(context.Background(), srv, func() error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+// RunH2C starts an HTTP server that also speaks unencrypted HTTP/2 (h2c),
+// e.g. behind a load balancer that terminates TLS. HTTP/2 clients must use
+// prior knowledge; the HTTP/1.1 Upgrade mechanism is not supported by net/http,
+// so such requests are answered over HTTP/1.1.
+func (engine *Engine) RunH2C(addr string) error {
+	engine.logStartup(addr)
+	srv := engine.newH2CServer(addr)
+	return engine.serve(context.Background(), srv, srv.ListenAndServe)
+}
+
+// RunListener starts the HTTP server on an existing listener
+func (engine *Engine) RunListener(ln net.Listener) error {
+	if err := engine.Validate(); err != nil {
+		ln.Close()
+		return err
+	}
+
+	addr := ln.Addr().String()
+	engine.logStartup(addr)
+	srv := engine.newServer(addr)
+	return engine.serve(context.Background(), srv, func() error {
+		return srv.Serve(ln)
+	})
+}
+
+// RunUnix starts the HTTP server on a Unix domain socket with the given file mode.
+// A stale socket file left behind by a previous process is removed first; the
+// socket file is removed again when the server shuts down.
+func (engine *Engine) RunUnix(path string, mode os.FileMode) error {
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return err
+	}
+
+	return engine.RunListener(ln)
+}
+
+// RunFd starts the HTTP server on an inherited listening socket file descriptor.
+// With systemd socket activation the first socket is fd 3; when LISTEN_FDS is
+// set the descriptor is checked against the passed sockets.
+func (engine *Engine) RunFd(fd int) error {
+	if err := checkListenFds(fd); err != nil {
+		return err
+	}
+
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+	if f == nil {
+		return fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer f.Close()
+
+	// FileListener duplicates the descriptor, so f can be closed afterwards
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return fmt.Errorf("file descriptor %d is not a listening socket: %w", fd, err)
+	}
+
+	return engine.RunListener(ln)
+}
+
+// Shutdown gracefully shuts down all servers started by the engine.
+// It stops accepting new connections, signals open SSE streams through
+// SSEWriter.Done and waits for active handlers to return until ctx expires.
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.beginShutdown()
+
+	engine.mu.Lock()
+	servers := make([]*http.Server, 0, len(engine.servers))
+	for srv := range engine.servers {
+		servers = append(servers, srv)
+	}
+	engine.mu.Unlock()
+
+	if engine.IsDebug() {
+		log.Printf("[DRIFT] Shutting down %d server(s)", len(servers))
+	}
+
+	var errs []error
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Done returns a channel that is closed when the engine starts shutting down
+func (engine *Engine) Done() <-chan struct{} {
+	return engine.shutdown
+}
+
+// beginShutdown signals that the engine is shutting down
+func (engine *Engine) beginShutdown() {
+	engine.shutdownOnce.Do(func() {
+		close(engine.shutdown)
+	})
+}
+
+// newServer creates an http.Server serving the engine
+func (engine *Engine) newServer(addr string) *http.Server {
+	config := engine.serverConfig
+
+	// Never run without limits in production
+	if engine.mode == ReleaseMode {
+		defaults := DefaultServerConfig()
+		if config.ReadHeaderTimeout == 0 {
+			config.ReadHeaderTimeout = defaults.ReadHeaderTimeout
+		}
+		if config.ReadTimeout == 0 {
+			config.ReadTimeout = defaults.ReadTimeout
+		}
+		if config.IdleTimeout == 0 {
+			config.IdleTimeout = defaults.IdleTimeout
+		}
+		if config.MaxHeaderBytes == 0 {
+			config.MaxHeaderBytes = defaults.MaxHeaderBytes
+		}
+	}
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           engine,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+		ErrorLog:          config.ErrorLog,
+	}
+}
+
+// newH2CServer creates an http.Server serving the engine over HTTP/1.1 and h2c
+func (engine *Engine) newH2CServer(addr string) *http.Server {
+	srv := engine.newServer(addr)
+
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	srv.Protocols = &protocols
+	return srv
+}
+
+// serve runs srv until it fails, is shut down, or ctx is done.
+// It doesn't start if routes were rejected during registration or the
+// engine is already shutting down.
+func (engine *Engine) serve(ctx context.Context, srv *http.Server, run func() error) error {
+	if err := engine.Validate(); err != nil {
+		return err
+	}
+
+	engine.mu.Lock()
+	engine.servers[srv] = struct{}{}
+	engine.mu.Unlock()
+
+	// Shutdown may have run before srv was registered. A closed server
+	// returns http.ErrServerClosed right away and closes its listener.
+	select {
+	case <-engine.Done():
+		srv.Close()
+	default:
+	}
+
+	defer func() {
+		engine.mu.Lock()
+		delete(engine.servers, srv)
+		engine.mu.Unlock()
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- run()
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		if engine.IsDebug() {
+			log.Printf("[DRIFT] Shutting down server on %s", srv.Addr)
+		}
+		engine.beginShutdown()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), engine.shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// logStartup logs server startup information in debug mode
+func (engine *Engine) logStartup(addr string) {
+	if engine.IsDebug() {
+		log.Printf("[DRIFT] Starting server in %s mode on %s", engine.mode, addr)
+		log.Printf("[DRIFT] Use engine.SetMode(drift.ReleaseMode) to disable debug logs")
+	}
+}
+
+// removeStaleSocket removes a Unix socket file that no process is listening on
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	// A successful dial means another server is still using the socket
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is already in use", path)
+	}
+
+	return os.Remove(path)
+}
+
+// listenFdsStart is the first file descriptor passed by systemd socket activation
+const listenFdsStart = 3
+
+// checkListenFds validates fd against the systemd LISTEN_FDS and LISTEN_PID variables
+func checkListenFds(fd int) error {
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return fmt.Errorf("LISTEN_PID %s does not match the current process", pid)
+	}
+
+	fds := os.Getenv("LISTEN_FDS")
+	if fds == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(fds)
+	if err != nil {
+		return fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+	if fd < listenFdsStart || fd >= listenFdsStart+n {
+		return fmt.Errorf("file descriptor %d was not passed by the service manager (LISTEN_FDS=%d)", fd, n)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package drift
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startH2C serves engine over HTTP/1.1 and h2c on a local port
+func startH2C(t *testing.T, engine *Engine) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := engine.newH2CServer(ln.Addr().String())
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return ln.Addr().String()
+}
+
+func TestH2CFlushesSSE(t *testing.T) {
+	engine := newTestEngine()
+	release := make(chan struct{})
+	engine.Get("/events", func(c *Context) {
+		sse := c.SSE()
+		sse.Send("first", "", "1")
+		<-release
+		sse.Send("second", "", "2")
+	})
+	addr := startH2C(t, engine)
+	unblock := sync.OnceFunc(func() { close(release) })
+	defer unblock()
+
+	// Prior knowledge client: HTTP/2 without TLS or upgrade
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+	resp, err := client.Get("http://" + addr + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("got %s, want HTTP/2", resp.Proto)
+	}
+
+	// The first event must arrive while the handler is still blocked
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	for _, want := range []string{"id: 1", "data: first"} {
+		select {
+		case line := <-lines:
+			if line != want {
+				t.Fatalf("got %q, want %q", line, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("first event was not flushed before the handler returned")
+		}
+	}
+
+	unblock()
+	var rest []string
+	for line := range lines {
+		rest = append(rest, line)
+	}
+	if got := strings.Join(rest, "\n"); got != "\nid: 2\ndata: second\n" {
+		t.Errorf("got rest of stream %q", got)
+	}
+}
+
+func TestH2CUpgradeServedOverHTTP1(t *testing.T) {
+	engine := newTestEngine()
+	engine.Get("/", func(c *Context) { c.String(http.StatusOK, "ok") })
+	addr := startH2C(t, engine)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/", nil)
+	req.Header.Set("Connection", "Upgrade, HTTP2-Settings")
+	req.Header.Set("Upgrade", "h2c")
+	req.Header.Set("HTTP2-Settings", "AAMAAABkAARAAAAAAAIAAAAA")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 1 {
+		t.Errorf("got %d over %s, want 200 over HTTP/1.1", resp.StatusCode, resp.Proto)
+	}
+}
+
+func TestShutdownBeforeRun(t *testing.T) {
+	engine := newTestEngine()
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- engine.RunListener(ln) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunListener = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		ln.Close()
+		t.Fatal("RunListener kept serving after Shutdown")
+	}
+	if _, err := ln.Accept(); err == nil {
+		t.Error("the listener was not closed")
+	}
+}
+
+func TestShutdownGraceful(t *testing.T) {
+	engine := newTestEngine()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	engine.Get("/slow", func(c *Context) {
+		close(started)
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+	engine.Get("/events", func(c *Context) {
+		sse := c.SSE()
+		sse.Send("hello", "", "")
+		<-sse.Done()
+		sse.Send("bye", "shutdown", "")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + ln.Addr().String()
+	runErr := make(chan error, 1)
+	go func() { runErr <- engine.RunListener(ln) }()
+
+	// An SSE stream and a slow request are in flight
+	events, err := http.Get(url + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Body.Close()
+	lines := bufio.NewScanner(events.Body)
+	if !lines.Scan() || lines.Text() != "data: hello" {
+		t.Fatalf("got %q, want the first event", lines.Text())
+	}
+
+	slow := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			t.Error(err)
+		}
+		slow <- resp
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- engine.Shutdown(context.Background()) }()
+
+	// The stream is told to finish
+	var rest []string
+	for lines.Scan() {
+		rest = append(rest, lines.Text())
+	}
+	if got := strings.Join(rest, "\n"); got != "\nevent: shutdown\ndata: bye\n" {
+		t.Errorf("got rest of stream %q", got)
+	}
+
+	// Shutdown waits for the slow request
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown returned %v before the request finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if resp := <-slow; resp != nil {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != "done" {
+			t.Errorf("slow request got %d %q, want 200 \"done\"", resp.StatusCode, body)
+		}
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Errorf("RunListener = %v, want nil", err)
+	}
+}
+
+func TestRunContextCancel(t *testing.T) {
+	engine := newTestEngine()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- engine.RunContext(ctx, "127.0.0.1:0") }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunContext = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext kept serving after its context was cancelled")
+	}
+	select {
+	case <-engine.Done():
+	default:
+		t.Error("Done was not closed")
+	}
+}
@@ -0,0 +1,149 @@
+package drift
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// SPAConfig defines the config for single-page application serving
+type SPAConfig struct {
+	// Index is the file served for client-side routes (default "index.html")
+	Index string
+
+	// Exclude lists request path prefixes that never fall back to the index,
+	// e.g. "/app/api". Prefixes match whole segments, so "/app/api" covers
+	// /app/api and /app/api/users but not /app/apiary. Requests under them
+	// get a 404.
+	Exclude []string
+
+	// AssetMaxAge is the cache lifetime of hashed assets (default 365 days)
+	AssetMaxAge time.Duration
+
+	// IsHashedAsset reports whether a file name contains a content hash and
+	// can be cached as immutable. The default matches names like
+	// app.3f9c2b1e.js and index-B4x9kQ2a.css.
+	IsHashedAsset func(name string) bool
+}
+
+// DefaultSPAConfig returns a default single-page application configuration
+func DefaultSPAConfig() SPAConfig {
+	return SPAConfig{
+		Index:         "index.html",
+		AssetMaxAge:   365 * 24 * time.Hour,
+		IsHashedAsset: isHashedAsset,
+	}
+}
+
+// SPA serves a single-page application from fsys under relativePath.
+// Existing files are served as-is, unknown paths return the index so the
+// client-side router can handle them, and missing files with an extension
+// (e.g. /app/assets/missing.js) still get a 404.
+func (group *RouterGroup) SPA(relativePath string, fsys fs.FS) {
+	group.SPAWithConfig(relativePath, fsys, DefaultSPAConfig())
+}
+
+// SPAWithConfig serves a single-page application with custom config.
+// The index is sent with "Cache-Control: no-cache" so new deployments are
+// picked up, hashed assets are cached as immutable.
+func (group *RouterGroup) SPAWithConfig(relativePath string, fsys fs.FS, config SPAConfig) {
+	if config.Index == "" {
+		config.Index = "index.html"
+	}
+	if config.AssetMaxAge == 0 {
+		config.AssetMaxAge = 365 * 24 * time.Hour
+	}
+	if config.IsHashedAsset == nil {
+		config.IsHashedAsset = isHashedAsset
+	}
+
+	handler := func(c *Context) {
+		for _, prefix := range config.Exclude {
+			if hasPathPrefix(c.Request.URL.Path, prefix) {
+				c.NotFound("")
+				return
+			}
+		}
+
+		name, ok := cleanFilePath(c.Param("filepath"))
+		if !ok {
+			c.NotFound("")
+			return
+		}
+
+		f, info, err := openFile(fsys, name)
+		if err == nil {
+			defer f.Close()
+		}
+		switch {
+		case err == nil && !info.IsDir():
+			switch {
+			case name == config.Index:
+				// Requested directly, the index must not go stale either
+				c.Header("Cache-Control", "no-cache")
+			case config.IsHashedAsset(name):
+				c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(config.AssetMaxAge.Seconds())))
+			}
+			serveContent(c, f, info, StaticConfig{})
+			return
+		case err != nil && !errors.Is(err, fs.ErrNotExist):
+			serveFileError(c, err)
+			return
+		case err != nil && path.Ext(name) != "":
+			// A missing asset must not be answered with the index
+			c.NotFound("")
+			return
+		}
+
+		index, indexInfo, err := openFile(fsys, config.Index)
+		if err != nil {
+			serveFileError(c, err)
+			return
+		}
+		defer index.Close()
+
+		c.Header("Cache-Control", "no-cache")
+		serveContent(c, index, indexInfo, StaticConfig{})
+	}
+
+	urlPattern := joinPaths(relativePath, "/*filepath")
+	group.Get(urlPattern, handler)
+}
+
+// hasPathPrefix reports whether p is prefix or below it, matching whole segments
+func hasPathPrefix(p, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// isHashedAsset reports whether the file name has a content hash before its
+// extension: at least 8 letters, digits or underscores including a digit,
+// separated by '.' or '-'
+func isHashedAsset(name string) bool {
+	base := path.Base(name)
+	base = strings.TrimSuffix(base, path.Ext(base))
+
+	i := strings.LastIndexAny(base, ".-")
+	if i < 0 {
+		return false
+	}
+	hash := base[i+1:]
+	if len(hash) < 8 {
+		return false
+	}
+
+	hasDigit := false
+	for _, c := range hash {
+		switch {
+		case c >= '0' && c <= '9':
+			hasDigit = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		default:
+			return false
+		}
+	}
+	return hasDigit
+}
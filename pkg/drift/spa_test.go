@@ -0,0 +1,72 @@
+package drift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSPAExclude(t *testing.T) {
+	dist := fstest.MapFS{
+		"index.html": {Data: []byte("index")},
+	}
+
+	app := newTestEngine()
+	app.SPAWithConfig("/app", dist, SPAConfig{
+		Exclude: []string{"/app/api", "/app/admin/"},
+	})
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/app/api", http.StatusNotFound},
+		{"/app/api/", http.StatusNotFound},
+		{"/app/api/users", http.StatusNotFound},
+		{"/app/apiary", http.StatusOK},
+		{"/app/apiary/hives", http.StatusOK},
+		{"/app/admin", http.StatusNotFound},
+		{"/app/admin/users", http.StatusNotFound},
+		{"/app/administrators", http.StatusOK},
+		{"/app/users/42", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := serve(app, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("GET %s: got %d, want %d", tt.path, w.Code, tt.code)
+		}
+	}
+}
+
+func TestSPACaching(t *testing.T) {
+	dist := fstest.MapFS{
+		"index.html":               {Data: []byte("index")},
+		"assets/index-B4x9kQ2a.js": {Data: []byte("hashed")},
+		"favicon.ico":              {Data: []byte("icon")},
+	}
+
+	app := newTestEngine()
+	app.SPA("/app", dist)
+
+	tests := []struct {
+		path         string
+		body         string
+		cacheControl string
+	}{
+		{"/app/", "index", "no-cache"},
+		{"/app/users/42", "index", "no-cache"},
+		{"/app/index.html", "index", "no-cache"},
+		{"/app/assets/index-B4x9kQ2a.js", "hashed", "public, max-age=31536000, immutable"},
+		{"/app/favicon.ico", "icon", ""},
+	}
+	for _, tt := range tests {
+		w := serve(app, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != tt.body {
+			t.Errorf("GET %s: got %d %q, want 200 %q", tt.path, w.Code, w.Body.String(), tt.body)
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("GET %s: Cache-Control = %q, want %q", tt.path, got, tt.cacheControl)
+		}
+	}
+}
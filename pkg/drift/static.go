@@ -0,0 +1,190 @@
+package drift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StaticConfig defines the config for static file serving
+type StaticConfig struct {
+	// Index is the file served for a directory (default "index.html")
+	Index string
+
+	// Browse enables listings for directories without an index file
+	Browse bool
+
+	// MaxAge sets "Cache-Control: public, max-age=..." when greater than zero
+	MaxAge time.Duration
+}
+
+// DefaultStaticConfig returns a default static file configuration
+func DefaultStaticConfig() StaticConfig {
+	return StaticConfig{
+		Index:  "index.html",
+		Browse: false,
+		MaxAge: 0,
+	}
+}
+
+// Static serves files from the given directory
+func (group *RouterGroup) Static(relativePath, root string) {
+	group.StaticFS(relativePath, os.DirFS(root))
+}
+
+// StaticFS serves files from the given file system, e.g. an embed.FS
+func (group *RouterGroup) StaticFS(relativePath string, fsys fs.FS) {
+	group.StaticFSWithConfig(relativePath, fsys, DefaultStaticConfig())
+}
+
+// StaticFSWithConfig serves files from the given file system with custom config.
+// Range and conditional requests (If-Modified-Since, If-None-Match) are supported.
+func (group *RouterGroup) StaticFSWithConfig(relativePath string, fsys fs.FS, config StaticConfig) {
+	if config.Index == "" {
+		config.Index = "index.html"
+	}
+
+	handler := func(c *Context) {
+		name, ok := cleanFilePath(c.Param("filepath"))
+		if !ok {
+			c.NotFound("")
+			return
+		}
+		serveFile(c, fsys, name, config)
+	}
+
+	urlPattern := joinPaths(relativePath, "/*filepath")
+	group.Get(urlPattern, handler)
+}
+
+// cleanFilePath turns a *filepath param into a name valid for fs.FS,
+// resolving any . and .. elements so the path cannot leave the root
+func cleanFilePath(filePath string) (string, bool) {
+	if strings.Contains(filePath, "\x00") || strings.Contains(filePath, "\\") {
+		return "", false
+	}
+	name := strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	if name == "" {
+		name = "."
+	}
+	return name, fs.ValidPath(name)
+}
+
+// serveFile serves the file or directory name from fsys
+func serveFile(c *Context, fsys fs.FS, name string, config StaticConfig) {
+	f, info, err := openFile(fsys, name)
+	if err != nil {
+		serveFileError(c, err)
+		return
+	}
+	defer f.Close()
+
+	if info.IsDir() {
+		// Redirect to the canonical directory URL so relative links work
+		if urlPath := c.Request.URL.Path; !strings.HasSuffix(urlPath, "/") {
+			c.Redirect(http.StatusMovedPermanently, path.Base(urlPath)+"/")
+			return
+		}
+
+		index, indexInfo, err := openFile(fsys, path.Join(name, config.Index))
+		if err == nil && !indexInfo.IsDir() {
+			defer index.Close()
+			serveContent(c, index, indexInfo, config)
+			return
+		}
+
+		if !config.Browse {
+			c.NotFound("")
+			return
+		}
+		if err := listDirectory(c, fsys, name); err != nil {
+			serveFileError(c, err)
+		}
+		return
+	}
+
+	serveContent(c, f, info, config)
+}
+
+// openFile opens a file and returns its info
+func openFile(fsys fs.FS, name string) (fs.File, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+// serveContent writes the file using http.ServeContent, which sets the
+// Content-Type and handles Range and conditional requests
+func serveContent(c *Context, f fs.File, info fs.FileInfo, config StaticConfig) {
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		// Not every fs.FS returns seekable files
+		data, err := io.ReadAll(f)
+		if err != nil {
+			serveFileError(c, err)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	if config.MaxAge > 0 {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(config.MaxAge.Seconds())))
+	}
+
+	c.written = true
+	http.ServeContent(c.Response, c.Request, info.Name(), info.ModTime(), content)
+}
+
+// listDirectory writes an HTML listing of a directory
+func listDirectory(c *Context, fsys fs.FS, name string) error {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var sb strings.Builder
+	sb.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(&sb, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(entryName))
+	}
+	sb.WriteString("</pre>\n")
+
+	return c.HTML(http.StatusOK, sb.String())
+}
+
+// serveFileError maps a file system error to an HTTP error
+func serveFileError(c *Context, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrInvalid):
+		c.NotFound("")
+	case errors.Is(err, fs.ErrPermission):
+		c.Forbidden("")
+	default:
+		c.InternalServerError("")
+	}
+}
@@ -0,0 +1,111 @@
+package drift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStaticFS(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	files := fstest.MapFS{
+		"css/site.css":    {Data: []byte("body{color:red}"), ModTime: modTime},
+		"docs/index.html": {Data: []byte("docs index"), ModTime: modTime},
+		"assets/app.js":   {Data: []byte("app"), ModTime: modTime},
+		"assets/a b.png":  {Data: []byte("png"), ModTime: modTime},
+	}
+
+	tests := []struct {
+		name     string
+		config   StaticConfig
+		path     string
+		header   map[string]string
+		code     int
+		body     string
+		response map[string]string
+	}{
+		{"file", StaticConfig{}, "/static/css/site.css", nil, http.StatusOK, "body{color:red}",
+			map[string]string{"Content-Type": "text/css; charset=utf-8", "Cache-Control": ""}},
+		{"max age", StaticConfig{MaxAge: time.Hour}, "/static/css/site.css", nil, http.StatusOK, "body{color:red}",
+			map[string]string{"Cache-Control": "public, max-age=3600"}},
+		{"dot dot inside root", StaticConfig{}, "/static/css/../css/site.css", nil, http.StatusOK, "body{color:red}", nil},
+		{"missing", StaticConfig{}, "/static/css/missing.css", nil, http.StatusNotFound, "", nil},
+		{"index", StaticConfig{}, "/static/docs/", nil, http.StatusOK, "docs index", nil},
+		{"directory redirect", StaticConfig{}, "/static/docs", nil, http.StatusMovedPermanently, "",
+			map[string]string{"Location": "/static/docs/"}},
+		{"no index", StaticConfig{}, "/static/assets/", nil, http.StatusNotFound, "", nil},
+		{"browse", StaticConfig{Browse: true}, "/static/assets/", nil, http.StatusOK, "", nil},
+		{"range", StaticConfig{}, "/static/css/site.css", map[string]string{"Range": "bytes=0-3"}, http.StatusPartialContent, "body",
+			map[string]string{"Content-Range": "bytes 0-3/15"}},
+		{"not modified", StaticConfig{}, "/static/css/site.css",
+			map[string]string{"If-Modified-Since": modTime.Add(time.Hour).Format(http.TimeFormat)}, http.StatusNotModified, "", nil},
+	}
+	for _, tt := range tests {
+		engine := newTestEngine()
+		var status int
+		engine.OnResponse(func(c *Context, info ResponseInfo) { status = info.Status })
+		engine.StaticFSWithConfig("/static", files, tt.config)
+
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		for key, value := range tt.header {
+			req.Header.Set(key, value)
+		}
+		w := serve(engine, req)
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if status != tt.code {
+			t.Errorf("%s: tracked status %d, want %d", tt.name, status, tt.code)
+		}
+		for key, value := range tt.response {
+			if got := w.Header().Get(key); got != value {
+				t.Errorf("%s: %s = %q, want %q", tt.name, key, got, value)
+			}
+		}
+		if tt.name == "browse" {
+			for _, link := range []string{`<a href="app.js">app.js</a>`, `<a href="a%20b.png">a b.png</a>`} {
+				if !strings.Contains(w.Body.String(), link) {
+					t.Errorf("listing %q is missing %s", w.Body.String(), link)
+				}
+			}
+		}
+	}
+}
+
+func TestStaticTraversal(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "public"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "public", "site.css"), []byte("css"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newTestEngine()
+	engine.Static("/static", filepath.Join(dir, "public"))
+
+	for _, path := range []string{
+		"/static/../secret.txt",
+		"/static/css/../../secret.txt",
+		"/static/%2e%2e/secret.txt",
+		"/static/%2E%2E/%2e%2e/secret.txt",
+		"/static/..%2fsecret.txt",
+		"/static/..%5csecret.txt",
+	} {
+		w := serve(engine, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code == http.StatusOK || strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("GET %s: got %d %q, want the file outside the root to stay hidden", path, w.Code, w.Body.String())
+		}
+	}
+	if w := serve(engine, httptest.NewRequest(http.MethodGet, "/static/site.css", nil)); w.Code != http.StatusOK {
+		t.Errorf("GET /static/site.css: got %d, want 200", w.Code)
+	}
+}
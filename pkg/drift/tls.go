@@ -0,0 +1,292 @@
+package drift
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TLSReloadConfig defines the certificates served by RunTLSReload
+type TLSReloadConfig struct {
+	// CertFile and KeyFile are the default certificate and key
+	CertFile string
+	KeyFile  string
+
+	// CertDir is a directory of additional certificates selected by SNI.
+	// Every <name>.crt or <name>.pem file with a matching <name>.key
+	// (or <name>-key.pem) file is loaded.
+	CertDir string
+
+	// PollInterval is how often the files are checked for changes (default 30s)
+	PollInterval time.Duration
+}
+
+// CertReloader serves TLS certificates loaded from files and reloads them
+// when the files change. Use GetCertificate in a tls.Config.
+type CertReloader struct {
+	config   TLSReloadConfig
+	certs    atomic.Pointer[[]*tls.Certificate]
+	modTimes map[string]time.Time
+	debug    bool
+}
+
+// NewCertReloader loads the configured certificates
+func NewCertReloader(config TLSReloadConfig) (*CertReloader, error) {
+	if config.CertFile == "" && config.CertDir == "" {
+		return nil, errors.New("TLS reload needs a CertFile or a CertDir")
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 30 * time.Second
+	}
+
+	r := &CertReloader{config: config}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	r.modTimes = modTimes
+	return r, nil
+}
+
+// GetCertificate returns the certificate matching the client's SNI name,
+// falling back to the default certificate
+func (r *CertReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certs := *r.certs.Load()
+	if len(certs) == 1 || hello.ServerName == "" {
+		return certs[0], nil
+	}
+	for _, cert := range certs {
+		if hello.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
+	}
+	return certs[0], nil
+}
+
+// Reload loads all certificates and swaps them in atomically.
+// On error the previously loaded certificates stay in use.
+func (r *CertReloader) Reload() error {
+	var certs []*tls.Certificate
+
+	if r.config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, &cert)
+	}
+
+	if r.config.CertDir != "" {
+		pairs, err := certPairs(r.config.CertDir)
+		if err != nil {
+			return err
+		}
+		for _, pair := range pairs {
+			cert, err := tls.LoadX509KeyPair(pair[0], pair[1])
+			if err != nil {
+				return fmt.Errorf("%s: %w", pair[0], err)
+			}
+			certs = append(certs, &cert)
+		}
+	}
+
+	if len(certs) == 0 {
+		return fmt.Errorf("no certificates found in %s", r.config.CertDir)
+	}
+
+	r.certs.Store(&certs)
+	return nil
+}
+
+// Watch polls the certificate files and reloads them when they change,
+// until stop is closed. Failed reloads are logged and the old certificates kept.
+func (r *CertReloader) Watch(stop <-chan struct{}) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			modTimes, err := r.stat()
+			if err != nil {
+				log.Printf("[DRIFT] TLS certificate check failed: %v", err)
+				continue
+			}
+			if sameModTimes(modTimes, r.modTimes) {
+				continue
+			}
+
+			// Remember the change even if the reload fails, so a broken
+			// certificate is reported once and retried on the next change
+			r.modTimes = modTimes
+			if err := r.Reload(); err != nil {
+				log.Printf("[DRIFT] TLS certificate reload failed, keeping the old certificates: %v", err)
+				continue
+			}
+			if r.debug {
+				log.Printf("[DRIFT] TLS certificates reloaded")
+			}
+		}
+	}
+}
+
+// stat returns the modification times of all certificate files
+func (r *CertReloader) stat() (map[string]time.Time, error) {
+	files := []string{}
+	if r.config.CertFile != "" {
+		files = append(files, r.config.CertFile, r.config.KeyFile)
+	}
+	if r.config.CertDir != "" {
+		pairs, err := certPairs(r.config.CertDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			files = append(files, pair[0], pair[1])
+		}
+	}
+
+	modTimes := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// RunTLSReload starts the HTTPS server with certificates that are reloaded
+// when their files change, so certificate rotation needs no restart
+func (engine *Engine) RunTLSReload(addr string, config TLSReloadConfig) error {
+	reloader, err := NewCertReloader(config)
+	if err != nil {
+		return err
+	}
+	reloader.debug = engine.IsDebug()
+
+	engine.logStartup(addr)
+	srv := engine.newServer(addr)
+	srv.TLSConfig = &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go reloader.Watch(stop)
+
+	return engine.serve(context.Background(), srv, func() error {
+		return srv.ListenAndServeTLS("", "")
+	})
+}
+
+// MutualTLSConfig defines how RunMutualTLS verifies client certificates
+type MutualTLSConfig struct {
+	// ClientCAFile is a PEM file with the CAs that sign client certificates
+	ClientCAFile string
+
+	// ClientCAs is used instead of ClientCAFile when set
+	ClientCAs *x509.CertPool
+
+	// ClientAuth is the verification mode (default tls.RequireAndVerifyClientCert)
+	ClientAuth tls.ClientAuthType
+}
+
+// RunMutualTLS starts the HTTPS server and requires clients to authenticate
+// with a certificate signed by one of the configured CAs. Use
+// middleware.ClientCert to turn the verified certificate into an identity.
+func (engine *Engine) RunMutualTLS(addr, certFile, keyFile string, config MutualTLSConfig) error {
+	clientCAs := config.ClientCAs
+	if clientCAs == nil {
+		if config.ClientCAFile == "" {
+			return errors.New("mutual TLS needs a ClientCAFile or ClientCAs")
+		}
+		caPEM, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("no certificates found in %s", config.ClientCAFile)
+		}
+	}
+
+	clientAuth := config.ClientAuth
+	if clientAuth == tls.NoClientCert {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	engine.logStartup(addr)
+	srv := engine.newServer(addr)
+	srv.TLSConfig = &tls.Config{
+		ClientCAs:  clientCAs,
+		ClientAuth: clientAuth,
+	}
+
+	return engine.serve(context.Background(), srv, func() error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+// certPairs returns the sorted certificate/key file pairs in dir
+func certPairs(dir string) ([][2]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs [][2]string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasSuffix(name, "-key.pem") {
+			continue
+		}
+
+		ext := filepath.Ext(name)
+		if ext != ".crt" && ext != ".pem" {
+			continue
+		}
+
+		base := strings.TrimSuffix(name, ext)
+		for _, keyName := range []string{base + ".key", base + "-key.pem"} {
+			keyFile := filepath.Join(dir, keyName)
+			if _, err := os.Stat(keyFile); err == nil {
+				pairs = append(pairs, [2]string{filepath.Join(dir, name), keyFile})
+				break
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0] < pairs[j][0]
+	})
+	return pairs, nil
+}
+
+// sameModTimes reports whether two sets of modification times are equal
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, modTime := range a {
+		if other, ok := b[file]; !ok || !other.Equal(modTime) {
+			return false
+		}
+	}
+	return true
+}
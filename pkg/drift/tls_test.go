@@ -0,0 +1,157 @@
+package drift
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for dnsName with the given
+// serial number and its key to certFile and keyFile
+func writeCert(t *testing.T, certFile, keyFile, dnsName string, serial int64) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// servedSerial makes a TLS handshake against r and returns the serial
+// number of the certificate served for serverName
+func servedSerial(t *testing.T, r *CertReloader, serverName string) int64 {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	server := tls.Server(serverConn, &tls.Config{GetCertificate: r.GetCertificate})
+	go func() {
+		server.Handshake()
+		server.Close()
+	}()
+
+	client := tls.Client(clientConn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	if err := client.Handshake(); err != nil {
+		t.Fatalf("handshake for %q: %v", serverName, err)
+	}
+	return client.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certDir := filepath.Join(dir, "certs")
+	if err := os.Mkdir(certDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	defaultCert, defaultKey := filepath.Join(dir, "default.crt"), filepath.Join(dir, "default.key")
+	apiCert, apiKey := filepath.Join(certDir, "api.crt"), filepath.Join(certDir, "api.key")
+	writeCert(t, defaultCert, defaultKey, "example.com", 1)
+	writeCert(t, apiCert, apiKey, "api.example.com", 2)
+	writeCert(t, filepath.Join(certDir, "www.pem"), filepath.Join(certDir, "www-key.pem"), "www.example.com", 3)
+
+	r, err := NewCertReloader(TLSReloadConfig{CertFile: defaultCert, KeyFile: defaultKey, CertDir: certDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkSerials := func(step string, want map[string]int64) {
+		t.Helper()
+		for serverName, serial := range want {
+			if got := servedSerial(t, r, serverName); got != serial {
+				t.Errorf("%s: served certificate %d for %q, want %d", step, got, serverName, serial)
+			}
+		}
+	}
+	checkSerials("initial", map[string]int64{
+		"":                    1, // no SNI
+		"api.example.com":     2,
+		"www.example.com":     3,
+		"unknown.example.com": 1,
+	})
+
+	// Rotated certificates are picked up by Reload
+	writeCert(t, apiCert, apiKey, "api.example.com", 20)
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	checkSerials("reloaded", map[string]int64{"": 1, "api.example.com": 20})
+
+	// A broken certificate keeps the previous ones in use
+	if err := os.WriteFile(apiCert, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Error("Reload of a corrupt certificate succeeded")
+	}
+	checkSerials("failed reload", map[string]int64{"": 1, "api.example.com": 20, "www.example.com": 3})
+}
+
+func TestCertReloaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeCert(t, certFile, keyFile, "example.com", 1)
+
+	r, err := NewCertReloader(TLSReloadConfig{CertFile: certFile, KeyFile: keyFile, PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		r.Watch(stop)
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	// Rewrite the files with a newer modification time
+	writeCert(t, certFile, keyFile, "example.com", 2)
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for servedSerial(t, r, "example.com") != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch did not reload the changed certificate")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"slices"
+
+	"github.com/m1z23r/drift/pkg/drift"
+)
+
+// ClientIdentity is the identity of a client authenticated by certificate
+type ClientIdentity struct {
+	CommonName     string
+	Organization   []string
+	DNSNames       []string
+	URIs           []string
+	EmailAddresses []string
+	Certificate    *x509.Certificate
+}
+
+// ClientCertConfig defines the config for client certificate middleware
+type ClientCertConfig struct {
+	// AllowedCommonNames restricts the accepted subject common names
+	AllowedCommonNames []string
+
+	// AllowedDNSNames restricts the accepted DNS SANs
+	AllowedDNSNames []string
+
+	// AllowedURIs restricts the accepted URI SANs (e.g. SPIFFE IDs)
+	AllowedURIs []string
+
+	// Validator is an additional check of the identity; returning false rejects the request
+	Validator func(*ClientIdentity) bool
+
+	// ContextKey is the key the identity is stored under in the context
+	ContextKey string
+}
+
+// DefaultClientCertConfig returns a default client certificate configuration
+// that accepts any verified certificate
+func DefaultClientCertConfig() ClientCertConfig {
+	return ClientCertConfig{
+		ContextKey: "client_identity",
+	}
+}
+
+// ClientCert returns a client certificate middleware with default config
+func ClientCert() drift.HandlerFunc {
+	return ClientCertWithConfig(DefaultClientCertConfig())
+}
+
+// ClientCertWithConfig returns a middleware that maps the verified client
+// certificate to a ClientIdentity stored in the context.
+// Requests without a verified certificate get a 401, certificates that are
+// not allowed get a 403.
+func ClientCertWithConfig(config ClientCertConfig) drift.HandlerFunc {
+	if config.ContextKey == "" {
+		config.ContextKey = "client_identity"
+	}
+
+	return func(c *drift.Context) {
+		// Only trust certificates verified against the client CAs
+		if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+			c.Unauthorized("Client certificate required")
+			return
+		}
+
+		identity := newClientIdentity(c.ClientCertificate())
+		if !config.allows(identity) {
+			c.Forbidden("Client certificate not allowed")
+			return
+		}
+
+		c.Set(config.ContextKey, identity)
+		c.Next()
+	}
+}
+
+// allows reports whether the identity passes the configured restrictions
+func (config ClientCertConfig) allows(identity *ClientIdentity) bool {
+	if len(config.AllowedCommonNames) > 0 && !slices.Contains(config.AllowedCommonNames, identity.CommonName) {
+		return false
+	}
+	if len(config.AllowedDNSNames) > 0 && !containsAny(config.AllowedDNSNames, identity.DNSNames) {
+		return false
+	}
+	if len(config.AllowedURIs) > 0 && !containsAny(config.AllowedURIs, identity.URIs) {
+		return false
+	}
+	if config.Validator != nil && !config.Validator(identity) {
+		return false
+	}
+	return true
+}
+
+// newClientIdentity extracts the subject and SANs of a certificate
+func newClientIdentity(cert *x509.Certificate) *ClientIdentity {
+	identity := &ClientIdentity{
+		CommonName:     cert.Subject.CommonName,
+		Organization:   cert.Subject.Organization,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Certificate:    cert,
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	return identity
+}
+
+// containsAny reports whether any of values is in allowed
+func containsAny(allowed, values []string) bool {
+	for _, value := range values {
+		if slices.Contains(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
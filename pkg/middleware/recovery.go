@@ -40,7 +40,8 @@ func Recovery() drift.HandlerFunc {
 	return RecoveryWithConfig(DefaultRecoveryConfig())
 }
 
-// RecoveryWithConfig returns a recovery middleware with custom config
+// RecoveryWithConfig returns a recovery middleware with custom config.
+// Recovered panics are reported to the engine's OnPanic hooks first.
 func RecoveryWithConfig(config RecoveryConfig) drift.HandlerFunc {
 	// Set defaults
 	if config.StackSize == 0 {
@@ -50,6 +51,8 @@ func RecoveryWithConfig(config RecoveryConfig) drift.HandlerFunc {
 	return func(c *drift.Context) {
 		defer func() {
 			if err := recover(); err != nil {
+				c.ReportPanic(err)
+
 				// Log the panic
 				if config.PrintStack {
 					stack := make([]byte, config.StackSize)
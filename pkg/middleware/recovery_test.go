@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/m1z23r/drift/pkg/drift"
+)
+
+func TestRecoveryReportsPanic(t *testing.T) {
+	engine := drift.New()
+	engine.SetMode(drift.ReleaseMode)
+	var recovered []any
+	var status int
+	engine.OnPanic(func(c *drift.Context, r any) { recovered = append(recovered, r) })
+	engine.OnResponse(func(c *drift.Context, info drift.ResponseInfo) { status = info.Status })
+	engine.Use(RecoveryWithConfig(RecoveryConfig{
+		Handler: func(c *drift.Context, err any) {
+			c.String(http.StatusServiceUnavailable, "recovered")
+		},
+	}))
+	engine.Get("/", func(c *drift.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "recovered" {
+		t.Errorf("got %d %q, want 503 \"recovered\"", w.Code, w.Body.String())
+	}
+	if len(recovered) != 1 || recovered[0] != "boom" {
+		t.Errorf("OnPanic got %v, want [boom] once", recovered)
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("OnResponse got status %d, want 503", status)
+	}
+}